@@ -0,0 +1,320 @@
+package mcstatus
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	defaultBedrockPingerOptions = BedrockPingerOptions{
+		Timeout:       time.Second * 5,
+		ClientGUID:    2,
+		ListenAddress: ":0",
+		OnResult:      nil,
+		BufferSize:    1024,
+	}
+)
+
+type BedrockPingerOptions struct {
+	Timeout       time.Duration
+	ClientGUID    int64
+	ListenAddress string
+	OnResult      func(result BedrockPingResult)
+	BufferSize    int
+}
+
+// BedrockPingResult is the outcome of a single ping sent by a BedrockPinger
+type BedrockPingResult struct {
+	Host   string                 `json:"host"`
+	Port   uint16                 `json:"port"`
+	Status *BedrockStatusResponse `json:"status"`
+	Error  error                  `json:"-"`
+}
+
+// BedrockPinger sends unconnected pings to many Bedrock servers from a single UDP socket. Pongs are matched
+// to their ping by the source address and the echoed time, and every ping produces exactly one result,
+// either a status or an error if no pong arrived within the timeout. Results are passed to the OnResult
+// function if one is set, which must not block, otherwise they are sent on the Results channel.
+type BedrockPinger struct {
+	Results  chan BedrockPingResult
+	conn     net.PacketConn
+	options  BedrockPingerOptions
+	pending  map[bedrockPingKey]time.Time
+	lock     sync.Mutex
+	lastTime int64
+	done     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+type bedrockPingKey struct {
+	addr     string
+	pingTime int64
+}
+
+// NewBedrockPinger opens the UDP socket used for pinging and starts receiving pongs
+func NewBedrockPinger(options ...BedrockPingerOptions) (*BedrockPinger, error) {
+	opts := parseBedrockPingerOptions(options...)
+
+	conn, err := net.ListenPacket("udp", opts.ListenAddress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &BedrockPinger{
+		conn:    conn,
+		options: opts,
+		pending: make(map[bedrockPingKey]time.Time),
+		done:    make(chan struct{}),
+	}
+
+	if opts.OnResult == nil {
+		p.Results = make(chan BedrockPingResult, opts.BufferSize)
+	}
+
+	p.wg.Add(2)
+
+	go p.receive()
+	go p.expire()
+
+	return p, nil
+}
+
+// Ping resolves the host and sends an unconnected ping to it
+func (p *BedrockPinger) Ping(host string, port uint16) error {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+
+	if err != nil {
+		return err
+	}
+
+	return p.PingAddr(addr)
+}
+
+// PingAddr sends an unconnected ping to the address
+func (p *BedrockPinger) PingAddr(addr *net.UDPAddr) error {
+	p.lock.Lock()
+
+	select {
+	case <-p.done:
+		{
+			p.lock.Unlock()
+
+			return ErrServerClosed
+		}
+	default:
+	}
+
+	// Every ping needs a unique time so that pongs can be matched to it, even when the same server is pinged twice
+	pingTime := time.Now().UnixNano() / int64(time.Millisecond)
+
+	if pingTime <= p.lastTime {
+		pingTime = p.lastTime + 1
+	}
+
+	p.lastTime = pingTime
+
+	key := bedrockPingKey{
+		addr:     addr.String(),
+		pingTime: pingTime,
+	}
+
+	p.pending[key] = time.Now()
+
+	p.lock.Unlock()
+
+	if err := writeBedrockPing(&datagramWriter{conn: p.conn, addr: addr}, pingTime, p.options.ClientGUID); err != nil {
+		p.lock.Lock()
+		delete(p.pending, key)
+		p.lock.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Close stops the pinger, pings that are still pending do not produce a result. If results are delivered
+// on the Results channel, it is closed once the pinger has stopped.
+func (p *BedrockPinger) Close() error {
+	err := ErrServerClosed
+
+	p.once.Do(func() {
+		p.lock.Lock()
+		close(p.done)
+		p.lock.Unlock()
+
+		err = p.conn.Close()
+
+		p.wg.Wait()
+
+		if p.Results != nil {
+			close(p.Results)
+		}
+	})
+
+	return err
+}
+
+func (p *BedrockPinger) receive() {
+	defer p.wg.Done()
+
+	data := make([]byte, 1<<16)
+
+	for {
+		n, addr, err := p.conn.ReadFrom(data)
+
+		if err != nil {
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+
+			// Errors such as ICMP port unreachable on some platforms are not fatal to the socket
+			time.Sleep(time.Millisecond * 10)
+
+			continue
+		}
+
+		pong, err := readBedrockPong(data[:n])
+
+		if err != nil {
+			continue
+		}
+
+		key := bedrockPingKey{
+			addr:     addr.String(),
+			pingTime: pong.PingTime,
+		}
+
+		p.lock.Lock()
+
+		sentAt, ok := p.pending[key]
+
+		if ok {
+			delete(p.pending, key)
+		}
+
+		p.lock.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		result := BedrockPingResult{}
+
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			result.Host = udpAddr.IP.String()
+			result.Port = uint16(udpAddr.Port)
+		}
+
+		status, err := parseBedrockStatus(pong.ServerGUID, pong.ServerID)
+
+		if err != nil {
+			result.Error = err
+		} else {
+			status.Latency = time.Since(sentAt)
+			status.Attempts = 1
+
+			result.Status = status
+		}
+
+		p.deliver(result)
+	}
+}
+
+func (p *BedrockPinger) expire() {
+	defer p.wg.Done()
+
+	interval := p.options.Timeout / 4
+
+	if interval < time.Millisecond*10 {
+		interval = time.Millisecond * 10
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		expired := make([]bedrockPingKey, 0)
+
+		p.lock.Lock()
+
+		for key, sentAt := range p.pending {
+			if time.Since(sentAt) >= p.options.Timeout {
+				expired = append(expired, key)
+
+				delete(p.pending, key)
+			}
+		}
+
+		p.lock.Unlock()
+
+		for _, key := range expired {
+			result := BedrockPingResult{
+				Error: ErrTimeout,
+			}
+
+			if host, port, err := net.SplitHostPort(key.addr); err == nil {
+				parsedPort, _ := strconv.ParseUint(port, 10, 16)
+
+				result.Host = host
+				result.Port = uint16(parsedPort)
+			}
+
+			p.deliver(result)
+		}
+	}
+}
+
+func (p *BedrockPinger) deliver(result BedrockPingResult) {
+	if p.options.OnResult != nil {
+		p.options.OnResult(result)
+
+		return
+	}
+
+	select {
+	case p.Results <- result:
+	case <-p.done:
+	}
+}
+
+func parseBedrockPingerOptions(opts ...BedrockPingerOptions) BedrockPingerOptions {
+	if len(opts) < 1 {
+		return defaultBedrockPingerOptions
+	}
+
+	options := opts[0]
+
+	// Pending pings are checked for expiry every few milliseconds, so without a timeout every ping would expire
+	// before the pong could arrive
+	if options.Timeout <= 0 {
+		options.Timeout = defaultBedrockPingerOptions.Timeout
+	}
+
+	if len(options.ListenAddress) < 1 {
+		options.ListenAddress = defaultBedrockPingerOptions.ListenAddress
+	}
+
+	// An unbuffered results channel would block receiving pongs whenever the results are read slowly
+	if options.BufferSize < 1 {
+		options.BufferSize = defaultBedrockPingerOptions.BufferSize
+	}
+
+	return options
+}
@@ -0,0 +1,143 @@
+package mcstatus_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestBedrockPinger(t *testing.T) {
+	online, err := net.ListenPacket("udp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer online.Close()
+
+	go serveTestBedrockPong(online, 0)
+
+	offline, err := net.ListenPacket("udp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer offline.Close()
+
+	pinger, err := mcstatus.NewBedrockPinger(mcstatus.BedrockPingerOptions{
+		Timeout:       time.Millisecond * 200,
+		ClientGUID:    2,
+		ListenAddress: "127.0.0.1:0",
+		BufferSize:    16,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pinger.Close()
+
+	for i := 0; i < 3; i++ {
+		if err = pinger.PingAddr(online.LocalAddr().(*net.UDPAddr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = pinger.PingAddr(offline.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatal(err)
+	}
+
+	successes, failures := 0, 0
+
+	for i := 0; i < 4; i++ {
+		result := <-pinger.Results
+
+		if result.Error != nil {
+			if result.Port != uint16(offline.LocalAddr().(*net.UDPAddr).Port) {
+				t.Fatalf("unexpected error for %s:%d: %v", result.Host, result.Port, result.Error)
+			}
+
+			failures++
+
+			continue
+		}
+
+		if result.Status.ServerGUID != 1234 || result.Status.Latency <= 0 {
+			t.Fatalf("unexpected status: %+v", result.Status)
+		}
+
+		successes++
+	}
+
+	if successes != 3 || failures != 1 {
+		t.Fatalf("expected 3 successes and 1 failure, got %d and %d", successes, failures)
+	}
+}
+
+// delayedPacketConn delays every datagram it writes, as if the peer was far away
+type delayedPacketConn struct {
+	net.PacketConn
+	delay time.Duration
+}
+
+func (c delayedPacketConn) WriteTo(data []byte, addr net.Addr) (int, error) {
+	time.Sleep(c.delay)
+
+	return c.PacketConn.WriteTo(data, addr)
+}
+
+func TestBedrockPingerDefaultTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestBedrockPong(delayedPacketConn{PacketConn: conn, delay: time.Millisecond * 50}, 0)
+
+	results := make(chan mcstatus.BedrockPingResult, 1)
+
+	// Only the result function is set, every other option uses its default
+	pinger, err := mcstatus.NewBedrockPinger(mcstatus.BedrockPingerOptions{
+		OnResult: func(result mcstatus.BedrockPingResult) {
+			results <- result
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pinger.Close()
+
+	if err = pinger.PingAddr(conn.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := <-results; result.Error != nil || result.Status.ServerGUID != 1234 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestBedrockPingerDefaultBufferSize(t *testing.T) {
+	// Only the timeout is set, so the results channel uses the default buffer size
+	pinger, err := mcstatus.NewBedrockPinger(mcstatus.BedrockPingerOptions{
+		Timeout:       time.Second,
+		ListenAddress: "127.0.0.1:0",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pinger.Close()
+
+	if size := cap(pinger.Results); size != 1024 {
+		t.Fatalf("expected a buffer of 1024 results, got %d", size)
+	}
+}
@@ -0,0 +1,260 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultBedrockStatusServerOptions = BedrockStatusServerOptions{
+		RateLimit: 20,
+		RateBurst: 40,
+	}
+)
+
+// BedrockServerInfo is the information sent by a server in an unconnected pong, it mirrors BedrockStatusResponse
+type BedrockServerInfo struct {
+	ServerGUID      int64  `json:"server_guid"`
+	Edition         string `json:"edition"`
+	MOTD            string `json:"motd"`
+	SubMOTD         string `json:"sub_motd"`
+	ProtocolVersion int64  `json:"protocol_version"`
+	Version         string `json:"version"`
+	OnlinePlayers   int64  `json:"online_players"`
+	MaxPlayers      int64  `json:"max_players"`
+	Gamemode        string `json:"gamemode"`
+	GamemodeID      int64  `json:"gamemode_id"`
+	PortIPv4        uint16 `json:"port_ipv4"`
+	PortIPv6        uint16 `json:"port_ipv6"`
+}
+
+// ServerID returns the semicolon separated server ID string of the information. Semicolons cannot be escaped
+// in the string, so they are removed from the values.
+func (i BedrockServerInfo) ServerID() string {
+	edition := i.Edition
+
+	if len(edition) < 1 {
+		edition = "MCPE"
+	}
+
+	values := []string{
+		edition,
+		i.MOTD,
+		strconv.FormatInt(i.ProtocolVersion, 10),
+		i.Version,
+		strconv.FormatInt(i.OnlinePlayers, 10),
+		strconv.FormatInt(i.MaxPlayers, 10),
+		strconv.FormatUint(uint64(i.ServerGUID), 10),
+		i.SubMOTD,
+		i.Gamemode,
+		strconv.FormatInt(i.GamemodeID, 10),
+		strconv.FormatUint(uint64(i.PortIPv4), 10),
+		strconv.FormatUint(uint64(i.PortIPv6), 10),
+	}
+
+	for k, v := range values {
+		values[k] = strings.ReplaceAll(v, ";", "")
+	}
+
+	return strings.Join(values, ";") + ";"
+}
+
+// ServerInfo returns the information of the status, so that the status of another server can be served as is
+func (r BedrockStatusResponse) ServerInfo() BedrockServerInfo {
+	info := BedrockServerInfo{
+		ServerGUID: r.ServerGUID,
+	}
+
+	if r.Edition != nil {
+		info.Edition = *r.Edition
+	}
+
+	if r.MOTD != nil {
+		lines := strings.SplitN(r.MOTD.Raw(), "\n", 2)
+
+		info.MOTD = lines[0]
+
+		if len(lines) > 1 {
+			info.SubMOTD = lines[1]
+		}
+	}
+
+	if r.ProtocolVersion != nil {
+		info.ProtocolVersion = *r.ProtocolVersion
+	}
+
+	if r.Version != nil {
+		info.Version = *r.Version
+	}
+
+	if r.OnlinePlayers != nil {
+		info.OnlinePlayers = *r.OnlinePlayers
+	}
+
+	if r.MaxPlayers != nil {
+		info.MaxPlayers = *r.MaxPlayers
+	}
+
+	if r.Gamemode != nil {
+		info.Gamemode = *r.Gamemode
+	}
+
+	if r.GamemodeID != nil {
+		info.GamemodeID = *r.GamemodeID
+	}
+
+	if r.PortIPv4 != nil {
+		info.PortIPv4 = *r.PortIPv4
+	}
+
+	if r.PortIPv6 != nil {
+		info.PortIPv6 = *r.PortIPv6
+	}
+
+	return info
+}
+
+// BedrockServerInfoProvider returns the information that is sent in response to a ping from the address
+type BedrockServerInfoProvider func(addr net.Addr) (*BedrockServerInfo, error)
+
+type BedrockStatusServerOptions struct {
+	RateLimit float64
+	RateBurst int
+}
+
+// BedrockStatusServer answers unconnected pings with the information from a provider, without accepting any
+// connections. Pings for open connections are only answered while the player count is below the maximum, the
+// same as a RakNet server. Pings over the rate limit of an IP are dropped.
+type BedrockStatusServer struct {
+	provider BedrockServerInfoProvider
+	options  BedrockStatusServerOptions
+	limiter  *rateLimiter
+	server   udpServer
+}
+
+// NewBedrockStatusServer creates a new Bedrock status server that answers pings with the information from the provider
+func NewBedrockStatusServer(provider BedrockServerInfoProvider, options ...BedrockStatusServerOptions) *BedrockStatusServer {
+	opts := parseBedrockStatusServerOptions(options...)
+
+	return &BedrockStatusServer{
+		provider: provider,
+		options:  opts,
+		limiter: &rateLimiter{
+			rate:  opts.RateLimit,
+			burst: float64(opts.RateBurst),
+		},
+	}
+}
+
+// ListenAndServe listens on the UDP address and answers unconnected pings
+func (s *BedrockStatusServer) ListenAndServe(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(conn)
+}
+
+// Serve answers unconnected pings received on the connection until the server is closed
+func (s *BedrockStatusServer) Serve(conn net.PacketConn) error {
+	return s.server.serve(conn, s.handle)
+}
+
+// Close stops answering pings and closes the connection
+func (s *BedrockStatusServer) Close() error {
+	return s.server.close()
+}
+
+func (s *BedrockStatusServer) handle(conn net.PacketConn, data []byte, addr net.Addr) {
+	// Packet ID, time, magic and client GUID
+	if len(data) < 33 || (data[0] != 0x01 && data[0] != 0x02) || !bytes.Equal(data[9:25], bedrockMagic) {
+		return
+	}
+
+	if !s.limiter.allow(addrIP(addr)) {
+		return
+	}
+
+	info, err := s.provider(addr)
+
+	if err != nil || info == nil {
+		return
+	}
+
+	if data[0] == 0x02 && info.OnlinePlayers >= info.MaxPlayers {
+		return
+	}
+
+	pingTime := int64(binary.BigEndian.Uint64(data[1:9]))
+
+	response, err := encodeBedrockPong(pingTime, info.ServerGUID, info.ServerID())
+
+	if err != nil {
+		return
+	}
+
+	conn.WriteTo(response, addr)
+}
+
+// encodeBedrockPong creates an unconnected pong packet
+// https://wiki.vg/Raknet_Protocol#Unconnected_Pong
+func encodeBedrockPong(pingTime, serverGUID int64, serverID string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// Packet ID - byte
+	if err := buf.WriteByte(0x1C); err != nil {
+		return nil, err
+	}
+
+	// Time - int64
+	if err := binary.Write(buf, binary.BigEndian, pingTime); err != nil {
+		return nil, err
+	}
+
+	// Server GUID - int64
+	if err := binary.Write(buf, binary.BigEndian, serverGUID); err != nil {
+		return nil, err
+	}
+
+	// Magic - bytes
+	if _, err := buf.Write(bedrockMagic); err != nil {
+		return nil, err
+	}
+
+	// Server ID - string
+	{
+		if len(serverID) > 0xFFFF {
+			serverID = serverID[:0xFFFF]
+		}
+
+		if err := binary.Write(buf, binary.BigEndian, uint16(len(serverID))); err != nil {
+			return nil, err
+		}
+
+		if _, err := buf.WriteString(serverID); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func parseBedrockStatusServerOptions(opts ...BedrockStatusServerOptions) BedrockStatusServerOptions {
+	if len(opts) < 1 {
+		return defaultBedrockStatusServerOptions
+	}
+
+	options := opts[0]
+
+	// A rate limit without a burst would drop every ping
+	if options.RateLimit > 0 && options.RateBurst < 1 {
+		options.RateBurst = defaultBedrockStatusServerOptions.RateBurst
+	}
+
+	return options
+}
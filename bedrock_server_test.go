@@ -0,0 +1,110 @@
+package mcstatus_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestBedrockStatusServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := mcstatus.BedrockServerInfo{
+		ServerGUID:      1234,
+		Edition:         "MCPE",
+		MOTD:            "§cUnder maintenance",
+		SubMOTD:         "Back soon",
+		ProtocolVersion: 649,
+		Version:         "1.20.61",
+		OnlinePlayers:   0,
+		MaxPlayers:      10,
+		Gamemode:        "Survival",
+		GamemodeID:      1,
+		PortIPv4:        19132,
+		PortIPv6:        19133,
+	}
+
+	server := mcstatus.NewBedrockStatusServer(func(addr net.Addr) (*mcstatus.BedrockServerInfo, error) {
+		return &info, nil
+	})
+
+	go server.Serve(conn)
+
+	defer server.Close()
+
+	response, err := mcstatus.StatusBedrock("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockStatusOptions{
+		EnableSRV:     false,
+		Timeout:       time.Second * 5,
+		ClientGUID:    2,
+		Samples:       1,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond * 100,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.MOTD == nil || response.MOTD.Clean() != "Under maintenance\nBack soon" {
+		t.Fatalf("unexpected MOTD: %v", response.MOTD)
+	}
+
+	if result := response.ServerInfo(); !reflect.DeepEqual(result, info) {
+		t.Fatalf("unexpected server info: %+v", result)
+	}
+}
+
+func TestBedrockServerInfoServerID(t *testing.T) {
+	info := mcstatus.BedrockServerInfo{
+		ServerGUID:      1234,
+		MOTD:            "A;B",
+		ProtocolVersion: 649,
+		Version:         "1.20.61",
+		MaxPlayers:      10,
+	}
+
+	if id := info.ServerID(); id != "MCPE;AB;649;1.20.61;0;10;1234;;;0;0;0;" {
+		t.Fatalf("unexpected server ID: %s", id)
+	}
+}
+
+func TestBedrockStatusServerDefaultBurst(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the rate limit is set, the burst uses its default
+	server := mcstatus.NewBedrockStatusServer(func(addr net.Addr) (*mcstatus.BedrockServerInfo, error) {
+		return &mcstatus.BedrockServerInfo{ServerGUID: 1234, Edition: "MCPE", MaxPlayers: 10}, nil
+	}, mcstatus.BedrockStatusServerOptions{RateLimit: 5})
+
+	go server.Serve(conn)
+
+	defer server.Close()
+
+	response, err := mcstatus.StatusBedrock("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockStatusOptions{
+		EnableSRV:     false,
+		Timeout:       time.Second,
+		ClientGUID:    2,
+		Samples:       1,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond * 100,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.ServerGUID != 1234 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const maxHistory = 1000
+
+// errInterrupted means the line was cancelled with Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads lines from a terminal in raw mode, with cursor movement and a history of previous lines
+// that is kept in a file between sessions
+type lineEditor struct {
+	in          *bufio.Reader
+	out         io.Writer
+	prompt      string
+	history     []string
+	historyFile string
+}
+
+func newLineEditor(in io.Reader, out io.Writer, prompt, historyFile string) *lineEditor {
+	e := &lineEditor{
+		in:          bufio.NewReader(in),
+		out:         out,
+		prompt:      prompt,
+		history:     make([]string, 0),
+		historyFile: historyFile,
+	}
+
+	if len(historyFile) > 0 {
+		if data, err := os.ReadFile(historyFile); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if line = strings.TrimRight(line, "\r"); len(line) > 0 {
+					e.history = append(e.history, line)
+				}
+			}
+		}
+
+		if len(e.history) > maxHistory {
+			e.history = e.history[len(e.history)-maxHistory:]
+		}
+	}
+
+	return e
+}
+
+// addHistory adds the line to the history and appends it to the history file, unless it is the same as
+// the previous line
+func (e *lineEditor) addHistory(line string) error {
+	if len(strings.TrimSpace(line)) < 1 {
+		return nil
+	}
+
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return nil
+	}
+
+	e.history = append(e.history, line)
+
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+
+	if len(e.historyFile) < 1 {
+		return nil
+	}
+
+	f, err := os.OpenFile(e.historyFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.WriteString(line + "\n")
+
+	return err
+}
+
+// readLine reads a single line, the terminal must be in raw mode. It returns io.EOF if Ctrl-D is pressed on an
+// empty line, and errInterrupted if Ctrl-C is pressed.
+func (e *lineEditor) readLine() (string, error) {
+	line := make([]rune, 0)
+	pos := 0
+
+	// The position in the history, where len(history) is the line being edited
+	historyPos := len(e.history)
+	edited := ""
+
+	previous := func() {
+		if historyPos < 1 {
+			return
+		}
+
+		if historyPos == len(e.history) {
+			edited = string(line)
+		}
+
+		historyPos--
+		line = []rune(e.history[historyPos])
+		pos = len(line)
+	}
+
+	next := func() {
+		if historyPos >= len(e.history) {
+			return
+		}
+
+		historyPos++
+
+		if historyPos == len(e.history) {
+			line = []rune(edited)
+		} else {
+			line = []rune(e.history[historyPos])
+		}
+
+		pos = len(line)
+	}
+
+	e.refresh(line, pos)
+
+	for {
+		r, _, err := e.in.ReadRune()
+
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				fmt.Fprint(e.out, "\r\n")
+
+				return string(line), nil
+			}
+
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			{
+				fmt.Fprint(e.out, "\r\n")
+
+				return string(line), nil
+			}
+		case 0x01: // Ctrl-A
+			pos = 0
+		case 0x05: // Ctrl-E
+			pos = len(line)
+		case 0x02: // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case 0x06: // Ctrl-F
+			if pos < len(line) {
+				pos++
+			}
+		case 0x03: // Ctrl-C
+			{
+				fmt.Fprint(e.out, "^C\r\n")
+
+				return "", errInterrupted
+			}
+		case 0x04: // Ctrl-D
+			{
+				if len(line) < 1 {
+					fmt.Fprint(e.out, "\r\n")
+
+					return "", io.EOF
+				}
+
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		case 0x7F, 0x08: // Backspace
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 0x0B: // Ctrl-K
+			line = line[:pos]
+		case 0x15: // Ctrl-U
+			{
+				line = line[pos:]
+				pos = 0
+			}
+		case 0x17: // Ctrl-W
+			{
+				start := pos
+
+				for start > 0 && unicode.IsSpace(line[start-1]) {
+					start--
+				}
+
+				for start > 0 && !unicode.IsSpace(line[start-1]) {
+					start--
+				}
+
+				line = append(line[:start], line[pos:]...)
+				pos = start
+			}
+		case 0x0C: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case 0x1B: // Escape sequence
+			{
+				key := e.readEscape()
+
+				switch key {
+				case "A":
+					previous()
+				case "B":
+					next()
+				case "C":
+					if pos < len(line) {
+						pos++
+					}
+				case "D":
+					if pos > 0 {
+						pos--
+					}
+				case "H", "1~", "7~":
+					pos = 0
+				case "F", "4~", "8~":
+					pos = len(line)
+				case "3~":
+					if pos < len(line) {
+						line = append(line[:pos], line[pos+1:]...)
+					}
+				}
+			}
+		case 0x10: // Ctrl-P
+			previous()
+		case 0x0E: // Ctrl-N
+			next()
+		default:
+			{
+				if !unicode.IsPrint(r) {
+					continue
+				}
+
+				line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+				pos++
+			}
+		}
+
+		e.refresh(line, pos)
+	}
+}
+
+// readEscape reads the rest of an escape sequence after the escape character, and returns the final part of
+// it such as "A" for the up arrow or "3~" for the delete key
+func (e *lineEditor) readEscape() string {
+	b, err := e.in.ReadByte()
+
+	if err != nil || (b != '[' && b != 'O') {
+		return ""
+	}
+
+	sequence := ""
+
+	for {
+		b, err := e.in.ReadByte()
+
+		if err != nil {
+			return ""
+		}
+
+		sequence += string(b)
+
+		// The sequence ends with a letter or a tilde, any digits before it are parameters
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || b == '~' {
+			return sequence
+		}
+
+		if len(sequence) > 8 {
+			return ""
+		}
+	}
+}
+
+// refresh redraws the prompt and the line, then moves the cursor to its position
+func (e *lineEditor) refresh(line []rune, pos int) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(line))
+
+	if back := len(line) - pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	input := strings.Join([]string{
+		"say helo\x1b[Dl\r",       // Left arrow, then insert a character
+		"time set day\x17night\r", // Ctrl-W deletes the previous word
+		"\x1b[A\x1b[A\r",          // Up arrow twice recalls the first line
+		"list\x03",                // Ctrl-C cancels the line
+		"\x04",                    // Ctrl-D on an empty line
+	}, "")
+
+	dir := t.TempDir()
+	historyFile := filepath.Join(dir, "history")
+
+	e := newLineEditor(strings.NewReader(input), io.Discard, "> ", historyFile)
+
+	expected := []string{"say hello", "time set night", "say hello"}
+
+	for _, v := range expected {
+		line, err := e.readLine()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line != v {
+			t.Fatalf("expected %q, got %q", v, line)
+		}
+
+		if err = e.addHistory(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := e.readLine(); err != errInterrupted {
+		t.Fatalf("expected errInterrupted, got %v", err)
+	}
+
+	if _, err := e.readLine(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	data, err := os.ReadFile(historyFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "say hello\ntime set night\nsay hello\n" {
+		t.Fatalf("unexpected history file: %q", data)
+	}
+
+	// The history is loaded again by a new editor
+	e = newLineEditor(strings.NewReader("\x1b[A\x1b[A\x1b[B\r"), io.Discard, "> ", historyFile)
+
+	if line, err := e.readLine(); err != nil || line != "say hello" {
+		t.Fatalf("unexpected line from history: %q, %v", line, err)
+	}
+}
@@ -0,0 +1,287 @@
+// Command mcrcon is an interactive RCON client for Minecraft servers. Commands are read from a line editor
+// with history when run in a terminal, or from the arguments, a script file or standard input otherwise.
+//
+//	mcrcon -H play.example.com -p mypassword
+//	mcrcon -H play.example.com -p mypassword "say Hello" "list"
+//	mcrcon -H play.example.com -p mypassword -s commands.txt
+//
+// The exit code is 0 on success, 1 if the connection failed, 2 if the password was incorrect and 3 if any
+// command failed.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+	"golang.org/x/term"
+)
+
+const (
+	exitOK = iota
+	exitConnection
+	exitAuthentication
+	exitCommand
+)
+
+type options struct {
+	host        string
+	port        uint
+	password    string
+	timeout     time.Duration
+	script      string
+	historyFile string
+	noColor     bool
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	opts := options{}
+
+	flag.StringVar(&opts.host, "H", "localhost", "host of the server")
+	flag.UintVar(&opts.port, "P", 25575, "RCON port of the server")
+	flag.StringVar(&opts.password, "p", os.Getenv("MCRCON_PASS"), "RCON password, defaults to the MCRCON_PASS environment variable")
+	flag.DurationVar(&opts.timeout, "t", time.Second*10, "timeout for connecting and for each command")
+	flag.StringVar(&opts.script, "s", "", "file to read commands from, one per line")
+	flag.StringVar(&opts.historyFile, "history", defaultHistoryFile(), "file to keep the command history in")
+	flag.BoolVar(&opts.noColor, "no-color", false, "print output without colors")
+	flag.Parse()
+
+	if opts.port > 0xFFFF {
+		fmt.Fprintf(os.Stderr, "mcrcon: invalid port: %d\n", opts.port)
+
+		return exitConnection
+	}
+
+	interactive := flag.NArg() < 1 && len(opts.script) < 1 && term.IsTerminal(int(os.Stdin.Fd()))
+
+	if len(opts.password) < 1 && interactive {
+		fmt.Fprint(os.Stderr, "Password: ")
+
+		password, err := term.ReadPassword(int(os.Stdin.Fd()))
+
+		fmt.Fprintln(os.Stderr)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mcrcon: %s\n", err)
+
+			return exitAuthentication
+		}
+
+		opts.password = string(password)
+	}
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial(opts.host, uint16(opts.port), mcstatus.RCONOptions{Timeout: opts.timeout}); err != nil {
+		fmt.Fprintf(os.Stderr, "mcrcon: failed to connect: %s\n", err)
+
+		return exitConnection
+	}
+
+	defer client.Close()
+
+	if err := client.Login(opts.password); err != nil {
+		fmt.Fprintf(os.Stderr, "mcrcon: failed to log in: %s\n", err)
+
+		if errors.Is(err, mcstatus.ErrInvalidPassword) {
+			return exitAuthentication
+		}
+
+		return exitConnection
+	}
+
+	if flag.NArg() > 0 {
+		return runCommands(client, flag.Args(), opts)
+	}
+
+	if len(opts.script) > 0 {
+		f, err := os.Open(opts.script)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mcrcon: %s\n", err)
+
+			return exitCommand
+		}
+
+		defer f.Close()
+
+		return runScript(client, f, opts)
+	}
+
+	if !interactive {
+		return runScript(client, os.Stdin, opts)
+	}
+
+	return runInteractive(client, opts)
+}
+
+// runCommands runs each of the commands in order, stopping at the first one that fails to send
+func runCommands(client *mcstatus.RCON, commands []string, opts options) int {
+	code := exitOK
+
+	for _, command := range commands {
+		result := execute(client, command, opts)
+
+		if result == exitConnection {
+			return exitConnection
+		}
+
+		if result != exitOK {
+			code = result
+		}
+	}
+
+	return code
+}
+
+// runScript runs every line of the reader as a command, blank lines and lines starting with # are skipped
+func runScript(client *mcstatus.RCON, r io.Reader, opts options) int {
+	commands := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) < 1 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		commands = append(commands, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "mcrcon: %s\n", err)
+
+		return exitCommand
+	}
+
+	return runCommands(client, commands, opts)
+}
+
+// runInteractive reads commands from the line editor until the user quits or the connection is lost
+func runInteractive(client *mcstatus.RCON, opts options) int {
+	fd := int(os.Stdin.Fd())
+
+	editor := newLineEditor(os.Stdin, os.Stdout, "> ", opts.historyFile)
+
+	fmt.Printf("Logged in to %s:%d, type Q or press Ctrl-D to quit\n", opts.host, opts.port)
+
+	code := exitOK
+
+	for {
+		state, err := term.MakeRaw(fd)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mcrcon: %s\n", err)
+
+			return exitCommand
+		}
+
+		line, err := editor.readLine()
+
+		term.Restore(fd, state)
+
+		if err == errInterrupted {
+			continue
+		}
+
+		if err != nil {
+			return code
+		}
+
+		line = strings.TrimSpace(line)
+
+		if len(line) < 1 {
+			continue
+		}
+
+		if line == "Q" || line == "quit" || line == "exit" {
+			return code
+		}
+
+		if err := editor.addHistory(line); err != nil {
+			fmt.Fprintf(os.Stderr, "mcrcon: failed to save history: %s\n", err)
+		}
+
+		result := execute(client, line, opts)
+
+		// The connection cannot be used after it failed, so there is no point in reading more commands
+		if result == exitConnection {
+			return exitConnection
+		}
+
+		if result != exitOK {
+			code = result
+		}
+	}
+}
+
+// execute runs the command and prints its output, the result is exitConnection if the connection failed,
+// and exitCommand if the command was too large to send, timed out or the server responded with an error
+func execute(client *mcstatus.RCON, command string, opts options) int {
+	output, err := client.ExecuteTimeout(command, opts.timeout)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mcrcon: %s\n", err)
+
+		// The connection can still be used after these errors
+		if errors.Is(err, mcstatus.ErrTimeout) || errors.Is(err, mcstatus.ErrPacketTooLarge) {
+			return exitCommand
+		}
+
+		return exitConnection
+	}
+
+	printOutput(os.Stdout, output, opts.noColor)
+
+	// A command that had no effect, such as making an operator an operator, is not considered a failure
+	if err = mcstatus.CheckRCONOutput(command, output); err != nil && !errors.Is(err, mcstatus.ErrNothingChanged) {
+		return exitCommand
+	}
+
+	return exitOK
+}
+
+// printOutput prints the output of a command with its formatting codes converted to terminal colors
+func printOutput(w io.Writer, output string, noColor bool) {
+	if len(output) < 1 {
+		return
+	}
+
+	motd, err := mcstatus.ParseMOTD(output)
+
+	if err != nil {
+		fmt.Fprintln(w, output)
+
+		return
+	}
+
+	if noColor {
+		fmt.Fprintln(w, motd.Clean())
+
+		return
+	}
+
+	fmt.Fprintln(w, motd.ANSI())
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".mcrcon_history")
+}
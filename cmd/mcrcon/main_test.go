@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestRunCommands(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		if command == "list" {
+			return "There are 0 of a max of 20 players online: "
+		}
+
+		return "Unknown or incomplete command, see below for error"
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	client := mcstatus.NewRCON()
+
+	if err = client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err = client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := options{timeout: time.Second * 5, noColor: true}
+
+	if code := runCommands(client, []string{"list"}, opts); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d", exitOK, code)
+	}
+
+	if code := runCommands(client, []string{"foo", "list"}, opts); code != exitCommand {
+		t.Fatalf("expected exit code %d for a failed command, got %d", exitCommand, code)
+	}
+
+	// Losing the connection is a connection error, not a failed command
+	server.Close()
+
+	if code := runCommands(client, []string{"list"}, opts); code != exitConnection {
+		t.Fatalf("expected exit code %d for a lost connection, got %d", exitConnection, code)
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	// Output is not a format string, so percent signs are printed as they are
+	printOutput(buf, "§aLoaded 100% of chunks", false)
+
+	if !bytes.Contains(buf.Bytes(), []byte("Loaded 100% of chunks")) {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
@@ -0,0 +1,234 @@
+package mcstatus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronDayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// CronSchedule is a parsed cron expression
+type CronSchedule struct {
+	expression string
+	second     uint64
+	minute     uint64
+	hour       uint64
+	dom        uint64
+	month      uint64
+	dow        uint64
+	// When both the day of the month and the day of the week are restricted, a day matching either is used
+	domAny bool
+	dowAny bool
+}
+
+// ParseCronSchedule parses a cron expression of five fields (minute, hour, day of month, month and day of
+// week), or six fields with seconds first. Fields support *, lists, ranges, steps and the names of months
+// and days, and the macros @hourly, @daily, @midnight, @weekly, @monthly, @yearly and @annually are supported.
+func ParseCronSchedule(expression string) (*CronSchedule, error) {
+	fields := strings.Fields(expression)
+
+	if len(fields) == 1 {
+		macro, ok := cronMacros[strings.ToLower(fields[0])]
+
+		if !ok {
+			return nil, fmt.Errorf("invalid cron expression %q: unknown macro", expression)
+		}
+
+		fields = strings.Fields(macro)
+	}
+
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	}
+
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 or 6 fields", expression)
+	}
+
+	s := &CronSchedule{
+		expression: expression,
+	}
+
+	var err error
+
+	if s.second, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+
+	if s.minute, err = parseCronField(fields[1], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+
+	if s.hour, err = parseCronField(fields[2], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+
+	if s.dom, err = parseCronField(fields[3], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+
+	if s.month, err = parseCronField(fields[4], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+
+	if s.dow, err = parseCronField(fields[5], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domAny = strings.HasPrefix(fields[3], "*") || fields[3] == "?"
+	s.dowAny = strings.HasPrefix(fields[5], "*") || fields[5] == "?"
+
+	return s, nil
+}
+
+// Next returns the first time after t that matches the schedule, in the location of t. The zero time is
+// returned if there is no such time within the next five years, such as for the 30th of February.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start at the next whole second
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+
+			continue
+		}
+
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *CronSchedule) String() string {
+	return s.expression
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if !s.domAny && !s.dowAny {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// parseCronField parses a single field of a cron expression into a bit set of the values it matches
+func parseCronField(field string, low, high int, names map[string]int) (uint64, error) {
+	var result uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			value, err := strconv.Atoi(part[i+1:])
+
+			if err != nil || value < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+
+			step = value
+			part = part[:i]
+		}
+
+		start, end := low, high
+
+		if part != "*" && part != "?" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			value, err := parseCronValue(bounds[0], names)
+
+			if err != nil {
+				return 0, err
+			}
+
+			start, end = value, value
+
+			if len(bounds) > 1 {
+				if end, err = parseCronValue(bounds[1], names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// A single value with a step, such as 5/15, runs from the value to the highest value
+				end = high
+			}
+		}
+
+		if start < low || end > high || start > end {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", part, low, high)
+		}
+
+		for v := start; v <= end; v += step {
+			result |= 1 << uint(v)
+		}
+	}
+
+	return result, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	return v, nil
+}
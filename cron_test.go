@@ -0,0 +1,47 @@
+package mcstatus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestCronSchedule(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 31, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2024, time.January, 31, 10, 30, 20, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 4 * * mon-fri", time.Date(2024, time.February, 1, 4, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"15,45 9-17/4 * * *", time.Date(2024, time.January, 31, 13, 15, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// When both days are restricted, either of them matches
+		{"0 12 1 * sat", time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := mcstatus.ParseCronSchedule(test.expression)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if next := schedule.Next(from); !next.Equal(test.expected) {
+			t.Fatalf("expected %q to be next at %s, got %s", test.expression, test.expected, next)
+		}
+	}
+
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "@often"} {
+		if _, err := mcstatus.ParseCronSchedule(expression); err == nil {
+			t.Fatalf("expected an error for %q", expression)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package mcstatus
 import (
 	"fmt"
 	"html"
+	"io"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
@@ -144,6 +146,8 @@ func (m MOTD) String() string {
 
 			if ok {
 				result += "\u00A7" + string(colorCode)
+			} else if isHexColor(v.Color) {
+				result += hexColorCode(v.Color)
 			}
 		}
 
@@ -201,6 +205,8 @@ func (m MOTD) HTML() string {
 
 		if ok {
 			styles["color"] = color
+		} else if isHexColor(v.Color) {
+			styles["color"] = strings.ToLower(v.Color)
 		}
 
 		if v.Obfuscated {
@@ -280,6 +286,8 @@ func (m MOTD) ANSI() string {
 
 		if color, ok := ansiColorLookupTable[v.Color]; ok {
 			attr = append(attr, color)
+		} else if isHexColor(v.Color) {
+			attr = append(attr, ansiColorLookupTable[nearestColorName(v.Color)])
 		}
 
 		if v.Bold {
@@ -298,7 +306,7 @@ func (m MOTD) ANSI() string {
 			attr = append(attr, color.Italic)
 		}
 
-		result += color.New(attr...).Sprintf(v.Text)
+		result += color.New(attr...).Sprint(v.Text)
 	}
 
 	return result
@@ -314,6 +322,8 @@ func parseChatObject(m map[string]interface{}) string {
 
 		if ok {
 			result += "\u00A7" + string(code)
+		} else if isHexColor(color) {
+			result += hexColorCode(color)
 		}
 	}
 
@@ -420,6 +430,11 @@ func parseString(s string) ([]FormatItem, error) {
 		{
 			name, ok := formattingColorCodeLookupTable[code]
 
+			// Hex colors are sent by Spigot and Paper as §x followed by each digit as a code, such as §x§f§f§0§0§0§0
+			if !ok && (code == 'x' || code == 'X') {
+				name, ok = readHexColor(r)
+			}
+
 			if ok {
 				if item.Obfuscated || item.Bold || item.Strikethrough || item.Underline || item.Italic || name != item.Color {
 					if len(item.Text) > 0 {
@@ -505,3 +520,81 @@ func parseString(s string) ([]FormatItem, error) {
 
 	return tree, nil
 }
+
+// readHexColor reads the six digits of a hex color code after §x and returns the color as "#rrggbb", the
+// reader is left unchanged if they are not valid
+func readHexColor(r *strings.Reader) (string, bool) {
+	start, _ := r.Seek(0, io.SeekCurrent)
+
+	result := "#"
+
+	for i := 0; i < 6; i++ {
+		section, _, err := r.ReadRune()
+
+		if err != nil || section != '\u00A7' {
+			break
+		}
+
+		digit, _, err := r.ReadRune()
+
+		if err != nil || !strings.ContainsRune("0123456789abcdefABCDEF", digit) {
+			break
+		}
+
+		result += strings.ToLower(string(digit))
+	}
+
+	if len(result) != 7 {
+		r.Seek(start, io.SeekStart)
+
+		return "", false
+	}
+
+	return result, true
+}
+
+// hexColorCode returns the formatting codes for a color in the "#rrggbb" format
+func hexColorCode(hex string) string {
+	result := "\u00A7x"
+
+	for _, digit := range strings.ToLower(hex[1:]) {
+		result += "\u00A7" + string(digit)
+	}
+
+	return result
+}
+
+func isHexColor(value string) bool {
+	if len(value) != 7 || value[0] != '#' {
+		return false
+	}
+
+	_, err := strconv.ParseUint(value[1:], 16, 32)
+
+	return err == nil
+}
+
+// nearestColorName returns the name of the formatting color closest to the hex color, for outputs that
+// only support the named colors
+func nearestColorName(hex string) string {
+	target, _ := strconv.ParseUint(hex[1:], 16, 32)
+
+	result := "white"
+	best := -1
+
+	for _, code := range "0123456789abcdef" {
+		name := formattingColorCodeLookupTable[code]
+		value, _ := strconv.ParseUint(htmlColorLookupTable[name][1:], 16, 32)
+
+		dr := int(target>>16&0xFF) - int(value>>16&0xFF)
+		dg := int(target>>8&0xFF) - int(value>>8&0xFF)
+		db := int(target&0xFF) - int(value&0xFF)
+
+		if distance := dr*dr + dg*dg + db*db; best < 0 || distance < best {
+			result = name
+			best = distance
+		}
+	}
+
+	return result
+}
@@ -1,6 +1,7 @@
 package mcstatus
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -11,3 +12,32 @@ func TestDescription(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestDescriptionHexColor(t *testing.T) {
+	motd, err := ParseMOTD("§x§f§f§8§0§0§0Orange§x§1Broken")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(motd.Tree) != 2 || motd.Tree[0].Color != "#ff8000" || motd.Tree[0].Text != "Orange" || motd.Tree[1].Color != "dark_blue" {
+		t.Fatalf("unexpected tree: %+v", motd.Tree)
+	}
+
+	// An incomplete hex color is ignored
+	if motd.Clean() != "OrangeBroken" {
+		t.Fatalf("unexpected clean description: %q", motd.Clean())
+	}
+
+	if html := motd.HTML(); !strings.Contains(html, "color: #ff8000;") {
+		t.Fatalf("unexpected HTML: %s", html)
+	}
+
+	if nearestColorName("#ff8000") != "gold" {
+		t.Fatalf("unexpected nearest color: %s", nearestColorName("#ff8000"))
+	}
+
+	if motd.String() != "§x§f§f§8§0§0§0Orange§1Broken" {
+		t.Fatalf("unexpected description: %q", motd.String())
+	}
+}
@@ -0,0 +1,302 @@
+package mcstatus
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	defaultBedrockDiscoveryOptions = BedrockDiscoveryOptions{
+		Timeout:       time.Second * 2,
+		ClientGUID:    2,
+		Ports:         []uint16{19132, 19133},
+		EnableIPv6:    true,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond * 500,
+	}
+	bedrockDiscoveryIPv6Group = net.ParseIP("ff02::1")
+)
+
+type BedrockDiscoveryOptions struct {
+	Timeout       time.Duration
+	ClientGUID    int64
+	Ports         []uint16
+	Hosts         []string
+	EnableIPv6    bool
+	MaxAttempts   int
+	RetryInterval time.Duration
+}
+
+// BedrockDiscoveryResult is a Bedrock server found on the local network, along with the address it answered from
+type BedrockDiscoveryResult struct {
+	Host   string                `json:"host"`
+	Port   uint16                `json:"port"`
+	Status BedrockStatusResponse `json:"status"`
+}
+
+// DiscoverBedrock finds Bedrock servers on the local network by broadcasting an unconnected ping to the ports
+// on every IPv4 broadcast address, and to the IPv6 all nodes multicast group, then collecting the pongs until
+// the timeout has passed. The ping is also sent to each of the hosts, for servers on networks that broadcasts
+// do not reach. Servers are deduplicated by their GUID, keeping the first address they answered from.
+func DiscoverBedrock(options ...BedrockDiscoveryOptions) ([]BedrockDiscoveryResult, error) {
+	opts := parseBedrockDiscoveryOptions(options...)
+
+	deadline := time.Now().Add(opts.Timeout)
+
+	conns := make([]*net.UDPConn, 0)
+	targets := make(map[*net.UDPConn][]*net.UDPAddr)
+
+	var ipv4Conn, ipv6Conn *net.UDPConn
+	var listenErr error
+
+	if conn, err := net.ListenUDP("udp4", &net.UDPAddr{}); err == nil {
+		ipv4Conn = conn
+		conns = append(conns, conn)
+		targets[conn] = bedrockDiscoveryIPv4Targets(opts.Ports)
+	} else {
+		listenErr = err
+	}
+
+	if opts.EnableIPv6 {
+		if conn, err := net.ListenUDP("udp6", &net.UDPAddr{}); err == nil {
+			ipv6Conn = conn
+			conns = append(conns, conn)
+			targets[conn] = bedrockDiscoveryIPv6Targets(opts.Ports)
+		} else if listenErr == nil {
+			listenErr = err
+		}
+	}
+
+	if len(conns) < 1 {
+		return nil, fmt.Errorf("failed to open a UDP socket for discovery: %w", listenErr)
+	}
+
+	for _, conn := range conns {
+		defer conn.Close()
+
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, host := range opts.Hosts {
+		for _, port := range opts.Ports {
+			addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+
+			if err != nil {
+				return nil, err
+			}
+
+			conn := ipv4Conn
+
+			if addr.IP.To4() == nil {
+				conn = ipv6Conn
+			}
+
+			if conn != nil {
+				targets[conn] = append(targets[conn], addr)
+			}
+		}
+	}
+
+	resultsLock := &sync.Mutex{}
+	results := make([]BedrockDiscoveryResult, 0)
+	seen := make(map[int64]struct{})
+
+	wg := &sync.WaitGroup{}
+
+	for _, conn := range conns {
+		wg.Add(1)
+
+		go (func(conn *net.UDPConn) {
+			defer wg.Done()
+
+			data := make([]byte, 1<<16)
+
+			for {
+				n, addr, err := conn.ReadFromUDP(data)
+
+				if err != nil {
+					return
+				}
+
+				pong, err := readBedrockPong(data[:n])
+
+				if err != nil {
+					continue
+				}
+
+				status, err := parseBedrockStatus(pong.ServerGUID, pong.ServerID)
+
+				if err != nil {
+					continue
+				}
+
+				resultsLock.Lock()
+
+				if _, ok := seen[pong.ServerGUID]; !ok {
+					seen[pong.ServerGUID] = struct{}{}
+
+					results = append(results, BedrockDiscoveryResult{
+						Host:   addr.IP.String(),
+						Port:   uint16(addr.Port),
+						Status: *status,
+					})
+				}
+
+				resultsLock.Unlock()
+			}
+		})(conn)
+	}
+
+	var sendErr error
+	sent := 0
+
+	attempts := opts.MaxAttempts
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	// Broadcasts are unreliable, so the ping is repeated with backoff for the duration of the window
+	for attempt := 1; attempt <= attempts; attempt++ {
+		for _, conn := range conns {
+			for _, target := range targets[conn] {
+				w := &datagramWriter{conn: conn, addr: target}
+
+				if err := writeBedrockPing(w, time.Now().UnixNano()/int64(time.Millisecond), opts.ClientGUID); err != nil {
+					sendErr = err
+
+					continue
+				}
+
+				sent++
+			}
+		}
+
+		if attempt >= attempts || opts.RetryInterval <= 0 {
+			break
+		}
+
+		next := time.Now().Add(retryBackoff(opts.RetryInterval, attempt))
+
+		if !next.Before(deadline) {
+			break
+		}
+
+		time.Sleep(time.Until(next))
+	}
+
+	if sent < 1 && sendErr != nil {
+		return nil, sendErr
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
+
+// bedrockDiscoveryIPv4Targets returns the limited broadcast address and the directed broadcast address of
+// every IPv4 network the host is on, for each of the ports
+func bedrockDiscoveryIPv4Targets(ports []uint16) []*net.UDPAddr {
+	ips := []net.IP{net.IPv4bcast}
+
+	if interfaces, err := net.Interfaces(); err == nil {
+		for _, iface := range interfaces {
+			if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 {
+				continue
+			}
+
+			addrs, err := iface.Addrs()
+
+			if err != nil {
+				continue
+			}
+
+			for _, addr := range addrs {
+				ipNet, ok := addr.(*net.IPNet)
+
+				if !ok {
+					continue
+				}
+
+				ip := ipNet.IP.To4()
+
+				if ip == nil || len(ipNet.Mask) != net.IPv4len {
+					continue
+				}
+
+				broadcast := make(net.IP, net.IPv4len)
+
+				for i := range ip {
+					broadcast[i] = ip[i] | ^ipNet.Mask[i]
+				}
+
+				ips = append(ips, broadcast)
+			}
+		}
+	}
+
+	targets := make([]*net.UDPAddr, 0)
+
+	for _, ip := range ips {
+		for _, port := range ports {
+			targets = append(targets, &net.UDPAddr{IP: ip, Port: int(port)})
+		}
+	}
+
+	return targets
+}
+
+// bedrockDiscoveryIPv6Targets returns the link local all nodes multicast group on every multicast capable
+// interface, for each of the ports
+func bedrockDiscoveryIPv6Targets(ports []uint16) []*net.UDPAddr {
+	targets := make([]*net.UDPAddr, 0)
+
+	interfaces, err := net.Interfaces()
+
+	if err != nil {
+		return targets
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+
+		for _, port := range ports {
+			targets = append(targets, &net.UDPAddr{IP: bedrockDiscoveryIPv6Group, Port: int(port), Zone: iface.Name})
+		}
+	}
+
+	return targets
+}
+
+func parseBedrockDiscoveryOptions(opts ...BedrockDiscoveryOptions) BedrockDiscoveryOptions {
+	if len(opts) < 1 {
+		return defaultBedrockDiscoveryOptions
+	}
+
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultBedrockDiscoveryOptions.Timeout
+	}
+
+	if len(options.Ports) < 1 {
+		options.Ports = defaultBedrockDiscoveryOptions.Ports
+	}
+
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = defaultBedrockDiscoveryOptions.MaxAttempts
+	}
+
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = defaultBedrockDiscoveryOptions.RetryInterval
+	}
+
+	return options
+}
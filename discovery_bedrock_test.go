@@ -0,0 +1,83 @@
+package mcstatus_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestDiscoverBedrock(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestBedrockPong(conn, 0)
+
+	results, err := mcstatus.DiscoverBedrock(mcstatus.BedrockDiscoveryOptions{
+		Timeout:       time.Millisecond * 500,
+		ClientGUID:    2,
+		Ports:         []uint16{uint16(conn.LocalAddr().(*net.UDPAddr).Port)},
+		Hosts:         []string{"127.0.0.1"},
+		EnableIPv6:    false,
+		MaxAttempts:   2,
+		RetryInterval: time.Millisecond * 100,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Other servers on the network may answer the broadcast too, so only the local server is checked
+	var result *mcstatus.BedrockDiscoveryResult
+
+	for i := range results {
+		if results[i].Status.ServerGUID == 1234 {
+			result = &results[i]
+		}
+	}
+
+	if result == nil || result.Host != "127.0.0.1" || result.Port != uint16(conn.LocalAddr().(*net.UDPAddr).Port) {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	if result.Status.OnlinePlayers == nil || *result.Status.OnlinePlayers != 2 {
+		t.Fatalf("unexpected status: %s", result.Status)
+	}
+}
+
+func TestDiscoverBedrockDefaultPorts(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:19132")
+
+	if err != nil {
+		t.Skipf("default Bedrock port is in use: %v", err)
+	}
+
+	defer conn.Close()
+
+	go serveTestBedrockPong(conn, 0)
+
+	// The ports are not set, so the default Bedrock ports are pinged
+	results, err := mcstatus.DiscoverBedrock(mcstatus.BedrockDiscoveryOptions{
+		Timeout:    time.Millisecond * 500,
+		Hosts:      []string{"127.0.0.1"},
+		EnableIPv6: false,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range results {
+		if result.Status.ServerGUID == 1234 && result.Port == 19132 {
+			return
+		}
+	}
+
+	t.Fatalf("unexpected results: %+v", results)
+}
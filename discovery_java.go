@@ -0,0 +1,246 @@
+package mcstatus
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	defaultJavaLANOptions = JavaLANOptions{
+		Timeout:  time.Second * 3,
+		Interval: time.Millisecond * 1500,
+	}
+	javaLANAddress      = &net.UDPAddr{IP: net.IPv4(224, 0, 2, 60), Port: 4445}
+	javaLANMOTDRegExp   = regexp.MustCompile(`\[MOTD\](.*?)\[/MOTD\]`)
+	javaLANAdvertRegExp = regexp.MustCompile(`\[AD\](.*?)\[/AD\]`)
+)
+
+type JavaLANOptions struct {
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+// JavaLANWorld is a world that was opened to LAN by a Java Edition client
+type JavaLANWorld struct {
+	MOTD MOTD   `json:"motd"`
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+}
+
+func (w JavaLANWorld) String() string {
+	return fmt.Sprintf("Host: %s\nPort: %d\nMOTD: %s", w.Host, w.Port, w.MOTD)
+}
+
+// JavaLANListener receives LAN world announcements sent to the Java Edition multicast group
+type JavaLANListener struct {
+	conn *net.UDPConn
+}
+
+// JavaLANAnnouncer periodically announces a LAN world to the Java Edition multicast group
+type JavaLANAnnouncer struct {
+	conn    *net.UDPConn
+	payload []byte
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+// ListenJavaLAN joins the Java Edition LAN multicast group and returns a listener for announcements
+func ListenJavaLAN() (*JavaLANListener, error) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, javaLANAddress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &JavaLANListener{
+		conn: conn,
+	}, nil
+}
+
+// Next blocks until the next valid announcement is received, invalid announcements are ignored
+func (l *JavaLANListener) Next() (*JavaLANWorld, error) {
+	data := make([]byte, 1500)
+
+	for {
+		n, addr, err := l.conn.ReadFromUDP(data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		world, err := parseJavaLANAnnouncement(data[:n])
+
+		if err != nil {
+			continue
+		}
+
+		world.Host = addr.IP.String()
+
+		return world, nil
+	}
+}
+
+// SetDeadline sets the time after which Next returns a timeout error
+func (l *JavaLANListener) SetDeadline(t time.Time) error {
+	return l.conn.SetReadDeadline(t)
+}
+
+func (l *JavaLANListener) Close() error {
+	return l.conn.Close()
+}
+
+// DiscoverJavaLAN listens for LAN world announcements until the timeout has passed, and returns every world
+// that was announced, deduplicated by the address of the world
+func DiscoverJavaLAN(options ...JavaLANOptions) ([]JavaLANWorld, error) {
+	opts := parseJavaLANOptions(options...)
+
+	l, err := ListenJavaLAN()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer l.Close()
+
+	if err = l.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
+		return nil, err
+	}
+
+	worlds := make([]JavaLANWorld, 0)
+	seen := make(map[string]struct{})
+
+	for {
+		world, err := l.Next()
+
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return worlds, nil
+			}
+
+			return nil, err
+		}
+
+		key := net.JoinHostPort(world.Host, strconv.Itoa(int(world.Port)))
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		worlds = append(worlds, *world)
+	}
+}
+
+// AnnounceJavaLAN starts announcing a LAN world with the MOTD and port to the Java Edition multicast group at
+// the interval, until the announcer is closed
+func AnnounceJavaLAN(motd string, port uint16, options ...JavaLANOptions) (*JavaLANAnnouncer, error) {
+	opts := parseJavaLANOptions(options...)
+
+	conn, err := net.DialUDP("udp4", nil, javaLANAddress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a := &JavaLANAnnouncer{
+		conn:    conn,
+		payload: []byte(fmt.Sprintf("[MOTD]%s[/MOTD][AD]%d[/AD]", motd, port)),
+		done:    make(chan struct{}),
+	}
+
+	if _, err = conn.Write(a.payload); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	a.wg.Add(1)
+
+	go (func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				{
+					a.conn.Write(a.payload)
+				}
+			case <-a.done:
+				{
+					return
+				}
+			}
+		}
+	})()
+
+	return a, nil
+}
+
+// Close stops announcing the world
+func (a *JavaLANAnnouncer) Close() error {
+	err := ErrServerClosed
+
+	a.once.Do(func() {
+		close(a.done)
+
+		a.wg.Wait()
+
+		err = a.conn.Close()
+	})
+
+	return err
+}
+
+// parseJavaLANAnnouncement parses the MOTD and port out of a LAN world announcement
+func parseJavaLANAnnouncement(data []byte) (*JavaLANWorld, error) {
+	motdMatch := javaLANMOTDRegExp.FindSubmatch(data)
+	advertMatch := javaLANAdvertRegExp.FindSubmatch(data)
+
+	if motdMatch == nil || advertMatch == nil {
+		return nil, ErrInvalidLANAnnouncement
+	}
+
+	port, err := strconv.ParseUint(string(advertMatch[1]), 10, 16)
+
+	if err != nil {
+		return nil, err
+	}
+
+	motd, err := ParseMOTD(string(motdMatch[1]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &JavaLANWorld{
+		MOTD: *motd,
+		Port: uint16(port),
+	}, nil
+}
+
+func parseJavaLANOptions(opts ...JavaLANOptions) JavaLANOptions {
+	if len(opts) < 1 {
+		return defaultJavaLANOptions
+	}
+
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultJavaLANOptions.Timeout
+	}
+
+	// A ticker with an interval of 0 panics, so the interval vanilla announces at is used instead
+	if options.Interval <= 0 {
+		options.Interval = defaultJavaLANOptions.Interval
+	}
+
+	return options
+}
@@ -0,0 +1,30 @@
+package mcstatus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseJavaLANAnnouncement(t *testing.T) {
+	world, err := parseJavaLANAnnouncement([]byte("[MOTD]§aPassTheMayo - New World[/MOTD][AD]41677[/AD]"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if world.Port != 41677 || world.MOTD.Clean() != "PassTheMayo - New World" {
+		t.Fatalf("unexpected world: %+v", world)
+	}
+
+	if _, err = parseJavaLANAnnouncement([]byte("[MOTD]New World[/MOTD]")); err != ErrInvalidLANAnnouncement {
+		t.Fatalf("expected invalid announcement error, got %v", err)
+	}
+}
+
+func TestParseJavaLANOptions(t *testing.T) {
+	opts := parseJavaLANOptions(JavaLANOptions{Timeout: time.Second})
+
+	if opts.Timeout != time.Second || opts.Interval != defaultJavaLANOptions.Interval {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
@@ -17,4 +17,43 @@ var (
 	ErrNotLoggedIn = errors.New("RCON client attempted to send message before successful login")
 	// ErrDecodeUTF16OddLength means a UTF-16 was attempted to be decoded from a byte array that was an odd length
 	ErrDecodeUTF16OddLength = errors.New("attempted to decode UTF-16 byte array with an odd length")
+	// ErrInvalidLANAnnouncement means a LAN world announcement did not contain a MOTD and port
+	ErrInvalidLANAnnouncement = errors.New("invalid LAN world announcement")
+	// ErrTimeout means the server did not respond before the timeout
+	ErrTimeout = errors.New("timed out waiting for a response from the server")
+	// ErrConnectionClosed means the RCON connection was closed while a command was waiting for a response
+	ErrConnectionClosed = errors.New("RCON connection has been closed")
+	// ErrPacketTooLarge means an RCON packet was larger than the max packet size
+	ErrPacketTooLarge = errors.New("RCON packet exceeds the max packet size")
+	// ErrUnknownServer means a command was sent to a server that has not been added to the RCON pool
+	ErrUnknownServer = errors.New("server has not been added to the RCON pool")
+	// ErrServerClosed means a server or proxy was used after it had been closed
+	ErrServerClosed = errors.New("server has been closed")
+	// ErrUnknownCommand means the server did not recognize the RCON command
+	ErrUnknownCommand = errors.New("unknown or incomplete command")
+	// ErrInvalidArgument means the server rejected an argument of the RCON command, or that an argument was not
+	// one of the values a command helper accepts
+	ErrInvalidArgument = errors.New("invalid argument for command")
+	// ErrInvalidPlayerName means a player name was not 1 to 16 letters, digits or underscores
+	ErrInvalidPlayerName = errors.New("invalid player name")
+	// ErrPlayerNotFound means the player the RCON command was run on does not exist
+	ErrPlayerNotFound = errors.New("player does not exist")
+	// ErrEntityNotFound means no entity matched the target of the RCON command
+	ErrEntityNotFound = errors.New("no entity was found")
+	// ErrNothingChanged means the RCON command had no effect, such as making a player an operator who already is one
+	ErrNothingChanged = errors.New("nothing changed")
+	// ErrCommandFailed means the server ran into an error while running the RCON command
+	ErrCommandFailed = errors.New("an unexpected error occurred while running the command")
+	// ErrCommandDenied means the RCON policy did not allow the caller to run the command
+	ErrCommandDenied = errors.New("command denied by RCON policy")
+	// ErrBroadcastAborted means a broadcast command was not sent to a server because it failed on another server
+	ErrBroadcastAborted = errors.New("broadcast was aborted after the command failed on another server")
+	// ErrJobExists means a job was added to an RCON scheduler that already has a job with the same name
+	ErrJobExists = errors.New("RCON scheduler already has a job with the name")
+	// ErrUnknownJob means a job was used that has not been added to the RCON scheduler
+	ErrUnknownJob = errors.New("job has not been added to the RCON scheduler")
+	// ErrGameNotSaved means the output of the save command of a backup did not report that the game was saved
+	ErrGameNotSaved = errors.New("server did not report that the game was saved")
+	// ErrInvalidSNBT means stringified NBT could not be parsed
+	ErrInvalidSNBT = errors.New("invalid SNBT")
 )
@@ -15,6 +15,20 @@ type Favicon struct {
 	exists bool
 }
 
+// NewFavicon encodes the image as a PNG favicon that can be sent to clients in a status response
+func NewFavicon(img image.Image) (*Favicon, error) {
+	buf := &bytes.Buffer{}
+
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+
+	return &Favicon{
+		raw:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+		exists: true,
+	}, nil
+}
+
 func (f Favicon) Exists() bool {
 	return f.exists
 }
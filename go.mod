@@ -2,7 +2,10 @@ module github.com/PassTheMayo/mcstatus/v3
 
 go 1.17
 
-require github.com/fatih/color v1.13.0
+require (
+	github.com/fatih/color v1.13.0
+	golang.org/x/term v0.1.0
+)
 
 require (
 	github.com/mattn/go-colorable v0.1.9 // indirect
@@ -0,0 +1,142 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+const (
+	maxHandshakeLength = 1 << 16
+)
+
+// Handshake is the first packet sent by a Java Edition client when it connects to a server
+type Handshake struct {
+	ProtocolVersion int32  `json:"protocol_version"`
+	Host            string `json:"host"`
+	Port            uint16 `json:"port"`
+	NextState       int32  `json:"next_state"`
+}
+
+// Hostname returns the host the client connected with, without any data appended by Forge or by BungeeCord IP forwarding
+func (h Handshake) Hostname() string {
+	host := h.Host
+
+	if i := strings.IndexByte(host, 0x00); i >= 0 {
+		host = host[:i]
+	}
+
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// ReadHandshake reads and parses a handshake packet sent by a Java Edition client
+func ReadHandshake(r io.Reader) (*Handshake, error) {
+	handshake, _, err := readHandshakePacket(r)
+
+	return handshake, err
+}
+
+// readHandshakePacket reads a handshake packet and returns the parsed handshake along with the raw
+// packet, including the length prefix, so that it can be replayed to another server
+func readHandshakePacket(r io.Reader) (*Handshake, []byte, error) {
+	var data []byte
+
+	// Packet length - varint
+	{
+		length, _, err := readVarInt(r)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if length < 1 || length > maxHandshakeLength {
+			return nil, nil, ErrUnexpectedResponse
+		}
+
+		data = make([]byte, length)
+
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	raw := &bytes.Buffer{}
+
+	if err := writePacket(bytes.NewBuffer(data), raw); err != nil {
+		return nil, nil, err
+	}
+
+	br := bytes.NewReader(data)
+
+	handshake := &Handshake{}
+
+	// Handshake packet
+	// https://wiki.vg/Server_List_Ping#Handshake
+	{
+		// Packet ID - varint
+		{
+			packetID, _, err := readVarInt(br)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if packetID != 0x00 {
+				return nil, nil, ErrUnexpectedResponse
+			}
+		}
+
+		// Protocol version - varint
+		{
+			protocolVersion, _, err := readVarInt(br)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			handshake.ProtocolVersion = protocolVersion
+		}
+
+		// Host - string
+		{
+			length, _, err := readVarInt(br)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if length < 0 || int(length) > br.Len() {
+				return nil, nil, ErrUnexpectedResponse
+			}
+
+			host := make([]byte, length)
+
+			if _, err := io.ReadFull(br, host); err != nil {
+				return nil, nil, err
+			}
+
+			handshake.Host = string(host)
+		}
+
+		// Port - uint16
+		{
+			if err := binary.Read(br, binary.BigEndian, &handshake.Port); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Next state - varint
+		{
+			nextState, _, err := readVarInt(br)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			handshake.NextState = nextState
+		}
+	}
+
+	return handshake, raw.Bytes(), nil
+}
@@ -0,0 +1,327 @@
+package mcstatus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	defaultProxyOptions = ProxyOptions{
+		Timeout:           time.Second * 5,
+		DisconnectMessage: "Unknown host",
+	}
+)
+
+// ProxyRoute maps a hostname sent by clients to the backend server that should receive the connection.
+// The host may be an exact hostname, a wildcard such as "*.example.com" matching any subdomain, or "*"
+// to act as the default route for connections that do not match any other route.
+type ProxyRoute struct {
+	Host          string `json:"host"`
+	Backend       string `json:"backend"`
+	ProxyProtocol int    `json:"proxy_protocol"`
+}
+
+type ProxyOptions struct {
+	Routes            []ProxyRoute
+	Timeout           time.Duration
+	DisconnectMessage string
+}
+
+// Proxy is a reverse proxy that routes Java Edition connections to a backend server based on the
+// hostname the client sent in its handshake
+type Proxy struct {
+	options   ProxyOptions
+	table     *proxyRouteTable
+	tableLock sync.RWMutex
+	server    tcpServer
+}
+
+type proxyRouteTable struct {
+	routes       []ProxyRoute
+	exact        map[string]ProxyRoute
+	wildcards    []proxyWildcardRoute
+	defaultRoute *ProxyRoute
+}
+
+// proxyWildcardRoute is a wildcard route along with the suffix that hostnames must end with, such as
+// ".example.com" for "*.example.com"
+type proxyWildcardRoute struct {
+	suffix string
+	route  ProxyRoute
+}
+
+// NewProxy creates a new hostname based reverse proxy from the options parameter
+func NewProxy(options ...ProxyOptions) *Proxy {
+	opts := parseProxyOptions(options...)
+
+	p := &Proxy{
+		options: opts,
+	}
+
+	p.SetRoutes(opts.Routes)
+
+	return p
+}
+
+// SetRoutes replaces the routing table, connections that are already established are not affected
+func (p *Proxy) SetRoutes(routes []ProxyRoute) {
+	table := &proxyRouteTable{
+		routes:    append(make([]ProxyRoute, 0, len(routes)), routes...),
+		exact:     make(map[string]ProxyRoute),
+		wildcards: make([]proxyWildcardRoute, 0),
+	}
+
+	for _, route := range routes {
+		host := strings.ToLower(strings.TrimSuffix(route.Host, "."))
+
+		switch {
+		case host == "*":
+			{
+				defaultRoute := route
+
+				table.defaultRoute = &defaultRoute
+			}
+		case strings.HasPrefix(host, "*."):
+			{
+				table.wildcards = append(table.wildcards, proxyWildcardRoute{
+					suffix: host[1:],
+					route:  route,
+				})
+			}
+		default:
+			{
+				table.exact[host] = route
+			}
+		}
+	}
+
+	// The most specific wildcard should always win, so longer suffixes are tried first
+	sort.SliceStable(table.wildcards, func(i, j int) bool {
+		return len(table.wildcards[i].suffix) > len(table.wildcards[j].suffix)
+	})
+
+	p.tableLock.Lock()
+	p.table = table
+	p.tableLock.Unlock()
+}
+
+// Routes returns the routes of the routing table, as they were set
+func (p *Proxy) Routes() []ProxyRoute {
+	p.tableLock.RLock()
+	defer p.tableLock.RUnlock()
+
+	return append(make([]ProxyRoute, 0, len(p.table.routes)), p.table.routes...)
+}
+
+// LoadRoutes reads a JSON array of routes from the file and replaces the routing table with it
+func (p *Proxy) LoadRoutes(path string) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	routes := make([]ProxyRoute, 0)
+
+	if err = json.Unmarshal(data, &routes); err != nil {
+		return err
+	}
+
+	p.SetRoutes(routes)
+
+	return nil
+}
+
+// Route returns the route that a connection using the hostname would be sent to
+func (p *Proxy) Route(host string) (*ProxyRoute, bool) {
+	host = Handshake{Host: host}.Hostname()
+
+	p.tableLock.RLock()
+	table := p.table
+	p.tableLock.RUnlock()
+
+	if route, ok := table.exact[host]; ok {
+		return &route, true
+	}
+
+	for _, wildcard := range table.wildcards {
+		if strings.HasSuffix(host, wildcard.suffix) {
+			route := wildcard.route
+
+			return &route, true
+		}
+	}
+
+	if table.defaultRoute != nil {
+		route := *table.defaultRoute
+
+		return &route, true
+	}
+
+	return nil, false
+}
+
+// ListenAndServe listens on the TCP address and proxies every accepted connection
+func (p *Proxy) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+
+	if err != nil {
+		return err
+	}
+
+	return p.Serve(l)
+}
+
+// Serve accepts connections from the listener and proxies them until the proxy is closed
+func (p *Proxy) Serve(l net.Listener) error {
+	return p.server.serve(l, p.handle)
+}
+
+// Close stops accepting connections and closes all connections that are being proxied
+func (p *Proxy) Close() error {
+	return p.server.close()
+}
+
+func (p *Proxy) handle(client net.Conn) {
+	if err := client.SetReadDeadline(time.Now().Add(p.options.Timeout)); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(client)
+
+	var route *ProxyRoute
+	var handshake []byte
+
+	// Legacy server list pings do not carry a hostname, so they always go to the default route
+	if b, err := r.Peek(1); err == nil && b[0] == 0xFE {
+		route, _ = p.Route("*")
+	} else {
+		h, raw, err := readHandshakePacket(r)
+
+		if err != nil {
+			return
+		}
+
+		var ok bool
+
+		if route, ok = p.Route(h.Host); !ok {
+			if h.NextState == 2 {
+				writeLoginDisconnect(client, p.options.DisconnectMessage)
+			}
+
+			return
+		}
+
+		handshake = raw
+	}
+
+	if route == nil {
+		return
+	}
+
+	backend, err := net.DialTimeout("tcp", route.Backend, p.options.Timeout)
+
+	if err != nil {
+		return
+	}
+
+	if !p.server.track(backend) {
+		backend.Close()
+
+		return
+	}
+
+	defer p.server.untrack(backend)
+	defer backend.Close()
+
+	if err = client.SetReadDeadline(time.Time{}); err != nil {
+		return
+	}
+
+	if route.ProxyProtocol > 0 {
+		if err = writeProxyProtocolHeader(backend, route.ProxyProtocol, client.RemoteAddr(), client.LocalAddr()); err != nil {
+			return
+		}
+	}
+
+	if _, err = backend.Write(handshake); err != nil {
+		return
+	}
+
+	pipeConns(client, r, backend)
+}
+
+// pipeConns copies data in both directions until either side closes its connection
+func pipeConns(client net.Conn, clientReader io.Reader, backend net.Conn) {
+	done := make(chan struct{}, 2)
+
+	go (func() {
+		io.Copy(backend, clientReader)
+
+		done <- struct{}{}
+	})()
+
+	go (func() {
+		io.Copy(client, backend)
+
+		done <- struct{}{}
+	})()
+
+	<-done
+
+	client.Close()
+	backend.Close()
+
+	<-done
+}
+
+// writeLoginDisconnect sends a disconnect packet to a client in the login state
+// https://wiki.vg/Protocol#Disconnect_.28login.29
+func writeLoginDisconnect(w io.Writer, message string) error {
+	buf := &bytes.Buffer{}
+
+	reason, err := json.Marshal(map[string]string{"text": message})
+
+	if err != nil {
+		return err
+	}
+
+	// Packet ID - varint
+	if _, err := writeVarInt(0x00, buf); err != nil {
+		return err
+	}
+
+	// Reason - string
+	if err := writeString(string(reason), buf); err != nil {
+		return err
+	}
+
+	return writePacket(buf, w)
+}
+
+func parseProxyOptions(opts ...ProxyOptions) ProxyOptions {
+	if len(opts) < 1 {
+		return defaultProxyOptions
+	}
+
+	options := opts[0]
+
+	// Routes can only be set through the options, so any field that was left empty uses its default
+	if options.Timeout <= 0 {
+		options.Timeout = defaultProxyOptions.Timeout
+	}
+
+	if len(options.DisconnectMessage) < 1 {
+		options.DisconnectMessage = defaultProxyOptions.DisconnectMessage
+	}
+
+	return options
+}
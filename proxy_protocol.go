@@ -0,0 +1,125 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+var (
+	proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+)
+
+// writeProxyProtocolHeader writes a PROXY protocol header describing the connection from source to destination
+// https://www.haproxy.org/download/2.8/doc/proxy-protocol.txt
+func writeProxyProtocolHeader(w io.Writer, version int, source, destination net.Addr) error {
+	src, srcOK := source.(*net.TCPAddr)
+	dst, dstOK := destination.(*net.TCPAddr)
+
+	switch version {
+	case 1:
+		{
+			if !srcOK || !dstOK {
+				_, err := io.WriteString(w, "PROXY UNKNOWN\r\n")
+
+				return err
+			}
+
+			family := "TCP4"
+
+			if src.IP.To4() == nil || dst.IP.To4() == nil {
+				family = "TCP6"
+			}
+
+			_, err := fmt.Fprintf(w, "PROXY %s %s %s %d %d\r\n", family, src.IP, dst.IP, src.Port, dst.Port)
+
+			return err
+		}
+	case 2:
+		{
+			buf := &bytes.Buffer{}
+
+			// Signature - bytes
+			if _, err := buf.Write(proxyProtocolV2Signature); err != nil {
+				return err
+			}
+
+			if !srcOK || !dstOK {
+				// Version and command (LOCAL) - byte
+				if err := buf.WriteByte(0x20); err != nil {
+					return err
+				}
+
+				// Family and protocol (UNSPEC) - byte
+				if err := buf.WriteByte(0x00); err != nil {
+					return err
+				}
+
+				// Length - uint16
+				if err := binary.Write(buf, binary.BigEndian, uint16(0)); err != nil {
+					return err
+				}
+
+				_, err := io.Copy(w, buf)
+
+				return err
+			}
+
+			// Version and command (PROXY) - byte
+			if err := buf.WriteByte(0x21); err != nil {
+				return err
+			}
+
+			srcIP, dstIP := src.IP.To4(), dst.IP.To4()
+
+			if srcIP != nil && dstIP != nil {
+				// Family and protocol (TCP over IPv4) - byte
+				if err := buf.WriteByte(0x11); err != nil {
+					return err
+				}
+			} else {
+				srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+
+				// Family and protocol (TCP over IPv6) - byte
+				if err := buf.WriteByte(0x21); err != nil {
+					return err
+				}
+			}
+
+			// Length - uint16
+			if err := binary.Write(buf, binary.BigEndian, uint16(len(srcIP)+len(dstIP)+4)); err != nil {
+				return err
+			}
+
+			// Source address - bytes
+			if _, err := buf.Write(srcIP); err != nil {
+				return err
+			}
+
+			// Destination address - bytes
+			if _, err := buf.Write(dstIP); err != nil {
+				return err
+			}
+
+			// Source port - uint16
+			if err := binary.Write(buf, binary.BigEndian, uint16(src.Port)); err != nil {
+				return err
+			}
+
+			// Destination port - uint16
+			if err := binary.Write(buf, binary.BigEndian, uint16(dst.Port)); err != nil {
+				return err
+			}
+
+			_, err := io.Copy(w, buf)
+
+			return err
+		}
+	default:
+		{
+			return fmt.Errorf("unknown PROXY protocol version: %d", version)
+		}
+	}
+}
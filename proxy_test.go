@@ -0,0 +1,300 @@
+package mcstatus_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestProxyRoute(t *testing.T) {
+	proxy := mcstatus.NewProxy(mcstatus.ProxyOptions{
+		Routes: []mcstatus.ProxyRoute{
+			{Host: "play.example.com", Backend: "10.0.0.1:25565"},
+			{Host: "*.example.com", Backend: "10.0.0.2:25565"},
+			{Host: "*.eu.example.com", Backend: "10.0.0.3:25565"},
+		},
+		Timeout: time.Second * 5,
+	})
+
+	tests := map[string]string{
+		"play.example.com":            "10.0.0.1:25565",
+		"PLAY.example.com.":           "10.0.0.1:25565",
+		"play.example.com\x00FML\x00": "10.0.0.1:25565",
+		"lobby.example.com":           "10.0.0.2:25565",
+		"lobby.eu.example.com":        "10.0.0.3:25565",
+	}
+
+	for host, backend := range tests {
+		route, ok := proxy.Route(host)
+
+		if !ok {
+			t.Fatalf("no route found for %q", host)
+		}
+
+		if route.Backend != backend {
+			t.Fatalf("expected %q to route to %s, got %s", host, backend, route.Backend)
+		}
+	}
+
+	// Wildcard routes are returned as they were set
+	if route, ok := proxy.Route("lobby.example.com"); !ok || route.Host != "*.example.com" {
+		t.Fatalf("unexpected route: %+v", route)
+	}
+
+	if routes := proxy.Routes(); len(routes) != 3 || routes[1].Host != "*.example.com" || routes[2].Host != "*.eu.example.com" {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+
+	if _, ok := proxy.Route("example.org"); ok {
+		t.Fatal("expected no route without a default route")
+	}
+
+	proxy.SetRoutes([]mcstatus.ProxyRoute{{Host: "*", Backend: "10.0.0.4:25565"}})
+
+	if route, ok := proxy.Route("example.org"); !ok || route.Backend != "10.0.0.4:25565" {
+		t.Fatal("expected default route after reload")
+	}
+}
+
+func TestProxyForward(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer backend.Close()
+
+	received := make(chan *mcstatus.Handshake, 1)
+
+	go (func() {
+		conn, err := backend.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		handshake, err := mcstatus.ReadHandshake(conn)
+
+		if err != nil {
+			received <- nil
+
+			return
+		}
+
+		received <- handshake
+
+		conn.Write([]byte("pong\n"))
+	})()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the routes are set, every other option uses its default
+	proxy := mcstatus.NewProxy(mcstatus.ProxyOptions{
+		Routes: []mcstatus.ProxyRoute{{Host: "*.example.com", Backend: backend.Addr().String()}},
+	})
+
+	go proxy.Serve(listener)
+
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	host := "mc.example.com"
+	packet := append([]byte{byte(len(host) + 6), 0x00, 0x2F, byte(len(host))}, host...)
+	packet = append(packet, 0x63, 0xDD, 0x01)
+
+	if _, err = conn.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+
+	var handshake *mcstatus.Handshake
+
+	select {
+	case handshake = <-received:
+	case <-time.After(time.Second * 5):
+		t.Fatal("connection was not forwarded to the backend")
+	}
+
+	if handshake == nil || handshake.Host != host || handshake.Port != 25565 || handshake.NextState != 1 {
+		t.Fatalf("backend received unexpected handshake: %+v", handshake)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if line != "pong\n" {
+		t.Fatalf("unexpected data from backend: %q", line)
+	}
+}
+
+// readTestProxyProtocolHeader proxies a connection from the loopback address to a backend with the PROXY protocol
+// version, and returns the header the backend received before the handshake along with the address of the
+// client and the address of the proxy it connected to
+func readTestProxyProtocolHeader(t *testing.T, loopback string, version int) ([]byte, *net.TCPAddr, *net.TCPAddr) {
+	backend, err := net.Listen("tcp", net.JoinHostPort(loopback, "0"))
+
+	if err != nil {
+		t.Skipf("loopback address %s is not available: %v", loopback, err)
+	}
+
+	defer backend.Close()
+
+	host := "mc.example.com"
+	packet := append([]byte{byte(len(host) + 6), 0x00, 0x2F, byte(len(host))}, host...)
+	packet = append(packet, 0x63, 0xDD, 0x01)
+
+	received := make(chan []byte, 1)
+
+	go (func() {
+		conn, err := backend.Accept()
+
+		if err != nil {
+			received <- nil
+
+			return
+		}
+
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+		r := bufio.NewReader(conn)
+
+		var header []byte
+
+		if version == 1 {
+			// Version 1 headers are a single line
+			header, err = r.ReadBytes('\n')
+		} else {
+			// Version 2 headers are 16 bytes followed by the length of the addresses
+			header = make([]byte, 16)
+
+			if _, err = io.ReadFull(r, header); err == nil {
+				addresses := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+
+				_, err = io.ReadFull(r, addresses)
+
+				header = append(header, addresses...)
+			}
+		}
+
+		// The header must be followed by the handshake, without any bytes in between
+		data := make([]byte, len(packet))
+
+		if _, readErr := io.ReadFull(r, data); err != nil || readErr != nil || !bytes.Equal(data, packet) {
+			received <- nil
+
+			return
+		}
+
+		received <- header
+	})()
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(loopback, "0"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := mcstatus.NewProxy(mcstatus.ProxyOptions{
+		Routes: []mcstatus.ProxyRoute{{Host: host, Backend: backend.Addr().String(), ProxyProtocol: version}},
+	})
+
+	go proxy.Serve(listener)
+
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if _, err = conn.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-received:
+		return data, conn.LocalAddr().(*net.TCPAddr), listener.Addr().(*net.TCPAddr)
+	case <-time.After(time.Second * 5):
+		t.Fatal("connection was not forwarded to the backend")
+	}
+
+	return nil, nil, nil
+}
+
+func TestProxyProtocolHeader(t *testing.T) {
+	signature := []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+	// encodeV2 creates a version 2 header from the family byte and the addresses in network byte order
+	encodeV2 := func(family byte, srcIP, dstIP net.IP, srcPort, dstPort int) []byte {
+		buf := &bytes.Buffer{}
+		buf.Write(signature)
+		buf.Write([]byte{0x21, family})
+		binary.Write(buf, binary.BigEndian, uint16(len(srcIP)+len(dstIP)+4))
+		buf.Write(srcIP)
+		buf.Write(dstIP)
+		binary.Write(buf, binary.BigEndian, uint16(srcPort))
+		binary.Write(buf, binary.BigEndian, uint16(dstPort))
+
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		loopback string
+		version  int
+		expected func(src, dst *net.TCPAddr) []byte
+	}{
+		{"v1 IPv4", "127.0.0.1", 1, func(src, dst *net.TCPAddr) []byte {
+			return []byte(fmt.Sprintf("PROXY TCP4 127.0.0.1 127.0.0.1 %d %d\r\n", src.Port, dst.Port))
+		}},
+		{"v1 IPv6", "::1", 1, func(src, dst *net.TCPAddr) []byte {
+			return []byte(fmt.Sprintf("PROXY TCP6 ::1 ::1 %d %d\r\n", src.Port, dst.Port))
+		}},
+		{"v2 IPv4", "127.0.0.1", 2, func(src, dst *net.TCPAddr) []byte {
+			return encodeV2(0x11, net.IP{127, 0, 0, 1}, net.IP{127, 0, 0, 1}, src.Port, dst.Port)
+		}},
+		{"v2 IPv6", "::1", 2, func(src, dst *net.TCPAddr) []byte {
+			return encodeV2(0x21, net.IPv6loopback, net.IPv6loopback, src.Port, dst.Port)
+		}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			data, src, dst := readTestProxyProtocolHeader(t, test.loopback, test.version)
+
+			if expected := test.expected(src, dst); data == nil || !bytes.Equal(data, expected) {
+				t.Fatalf("unexpected header:\n%q\nexpected:\n%q", data, expected)
+			}
+		})
+	}
+}
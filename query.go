@@ -8,21 +8,26 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
 var (
 	sessionID           int32 = 0
 	defaultQueryOptions       = QueryOptions{
-		Timeout:   time.Second * 5,
-		SessionID: 0,
+		Timeout:       time.Second * 5,
+		SessionID:     0,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond * 500,
 	}
 	magic = []byte{0xFE, 0xFD}
 )
 
 type QueryOptions struct {
-	Timeout   time.Duration
-	SessionID int32
+	Timeout       time.Duration
+	SessionID     int32
+	MaxAttempts   int
+	RetryInterval time.Duration
 }
 
 type BasicQueryResponse struct {
@@ -33,6 +38,7 @@ type BasicQueryResponse struct {
 	MaxPlayers    uint64
 	HostPort      uint16
 	HostIP        string
+	Attempts      int
 }
 
 func (r BasicQueryResponse) String() string {
@@ -48,8 +54,9 @@ func (r BasicQueryResponse) String() string {
 }
 
 type FullQueryResponse struct {
-	Data    map[string]string
-	Players []string
+	Data     map[string]string
+	Players  []string
+	Attempts int
 }
 
 func (r FullQueryResponse) String() string {
@@ -80,467 +87,457 @@ func BasicQuery(host string, port uint16, options ...QueryOptions) (*BasicQueryR
 
 	defer conn.Close()
 
-	r := bufio.NewReader(conn)
+	deadline := time.Now().Add(opts.Timeout)
 
-	if err = conn.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
+	if err = conn.SetDeadline(deadline); err != nil {
 		return nil, err
 	}
 
-	// Handshake request packet
-	// https://wiki.vg/Query#Request
-	{
-		buf := &bytes.Buffer{}
+	sessionID := opts.SessionID & 0x0F0F0F0F
 
-		// Magic - uint16
-		if _, err := buf.Write(magic); err != nil {
-			return nil, err
-		}
+	challengeToken, attempts, err := queryHandshake(conn, sessionID, deadline, opts)
 
-		// Type - byte
-		if err := buf.WriteByte(0x09); err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Session ID - int32
-		if err := binary.Write(buf, binary.BigEndian, opts.SessionID&0x0F0F0F0F); err != nil {
-			return nil, err
-		}
+	data, statAttempts, err := queryStat(conn, sessionID, challengeToken, false, deadline, opts)
 
-		if _, err := io.Copy(conn, buf); err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	var challengeToken int32
+	response, err := parseBasicQueryResponse(data)
 
-	// Handshake response packet
-	// https://wiki.vg/Query#Response
-	{
-		// Type - byte
-		{
-			v, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	response.Attempts = attempts + statAttempts
 
-			if v != 0x09 {
-				return nil, ErrUnexpectedResponse
-			}
-		}
+	return response, nil
+}
 
-		// Session ID - int32
-		{
-			var sessionID int32
+// FullQuery runs a query on the server and returns the full information
+func FullQuery(host string, port uint16, options ...QueryOptions) (*FullQueryResponse, error) {
+	opts := parseQueryOptions(options...)
 
-			if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
-				return nil, err
-			}
+	conn, err := net.DialTimeout("udp", fmt.Sprintf("%s:%d", host, port), opts.Timeout)
 
-			if sessionID != opts.SessionID {
-				return nil, ErrUnexpectedResponse
-			}
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Challenge Token - string
-		{
-			data, err := r.ReadBytes(0x00)
+	defer conn.Close()
 
-			if err != nil {
-				return nil, err
-			}
+	deadline := time.Now().Add(opts.Timeout)
 
-			v, err := strconv.ParseInt(string(data[:len(data)-1]), 10, 32)
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	sessionID := opts.SessionID & 0x0F0F0F0F
 
-			challengeToken = int32(v)
-		}
+	challengeToken, attempts, err := queryHandshake(conn, sessionID, deadline, opts)
+
+	if err != nil {
+		return nil, err
 	}
 
-	// Basic stat request packet
-	// https://wiki.vg/Query#Request_2
-	{
-		buf := &bytes.Buffer{}
+	data, statAttempts, err := queryStat(conn, sessionID, challengeToken, true, deadline, opts)
 
-		// Magic - uint16
-		if _, err := buf.Write(magic); err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Type - byte
-		if err := buf.WriteByte(0x00); err != nil {
-			return nil, err
-		}
+	response, err := parseFullQueryResponse(data)
 
-		// Session ID - int32
-		if err := binary.Write(buf, binary.BigEndian, opts.SessionID&0x0F0F0F0F); err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Challenge Token - int32
-		if err := binary.Write(buf, binary.BigEndian, challengeToken); err != nil {
-			return nil, err
-		}
+	response.Attempts = attempts + statAttempts
 
-		if _, err := io.Copy(conn, buf); err != nil {
-			return nil, err
-		}
-	}
+	return response, nil
+}
 
-	response := BasicQueryResponse{}
+// queryHandshake requests a challenge token from the server, retransmitting the request if it is lost
+func queryHandshake(conn net.Conn, sessionID int32, deadline time.Time, opts QueryOptions) (int32, int, error) {
+	request, err := encodeQueryHandshakeRequest(sessionID)
 
-	// Basic stat response packet
-	// https://wiki.vg/Query#Response_2
-	{
-		// Type - byte
-		{
-			v, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	data, attempts, err := exchangeUDP(conn, deadline, opts.MaxAttempts, opts.RetryInterval, func(int) []byte { return request }, queryResponseMatcher(0x09, sessionID))
 
-			if v != 0x00 {
-				return nil, ErrUnexpectedResponse
-			}
-		}
+	if err != nil {
+		return 0, attempts, err
+	}
 
-		// Session ID - int32
-		{
-			var sessionID int32
+	challengeToken, err := parseQueryHandshakeResponse(data)
 
-			if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
-				return nil, err
-			}
+	return challengeToken, attempts, err
+}
 
-			if sessionID != opts.SessionID {
-				return nil, ErrUnexpectedResponse
-			}
-		}
+// queryStat requests the basic or full stat of the server, retransmitting the request if it is lost. A
+// response to a retransmitted handshake may arrive after the first one, in which case the server has replaced
+// the challenge token, so the token of the latest handshake response is used for every request.
+func queryStat(conn net.Conn, sessionID, challengeToken int32, full bool, deadline time.Time, opts QueryOptions) ([]byte, int, error) {
+	request, err := encodeQueryStatRequest(sessionID, challengeToken, full)
 
-		// MOTD - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+	if err != nil {
+		return nil, 0, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	isHandshake := queryResponseMatcher(0x09, sessionID)
+	isStat := queryResponseMatcher(0x00, sessionID)
 
-			description, err := ParseMOTD(decodeASCII(data[:len(data)-1]))
+	return exchangeUDP(conn, deadline, opts.MaxAttempts, opts.RetryInterval, func(int) []byte { return request }, func(data []byte) bool {
+		if !isHandshake(data) {
+			return isStat(data)
+		}
 
-			if err != nil {
-				return nil, err
+		// The next retransmission of the request uses the newer token
+		if token, err := parseQueryHandshakeResponse(data); err == nil && token != challengeToken {
+			if newRequest, err := encodeQueryStatRequest(sessionID, token, full); err == nil {
+				challengeToken = token
+				request = newRequest
 			}
-
-			response.MOTD = *description
 		}
 
-		// Game Type - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+		return false
+	})
+}
 
-			if err != nil {
-				return nil, err
-			}
+// encodeQueryHandshakeRequest creates a handshake request packet
+// https://wiki.vg/Query#Request
+func encodeQueryHandshakeRequest(sessionID int32) ([]byte, error) {
+	buf := &bytes.Buffer{}
 
-			response.GameType = string(data[:len(data)-1])
-		}
+	// Magic - uint16
+	if _, err := buf.Write(magic); err != nil {
+		return nil, err
+	}
 
-		// Map - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+	// Type - byte
+	if err := buf.WriteByte(0x09); err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	// Session ID - int32
+	if err := binary.Write(buf, binary.BigEndian, sessionID); err != nil {
+		return nil, err
+	}
 
-			response.Map = string(data[:len(data)-1])
-		}
+	return buf.Bytes(), nil
+}
 
-		// Online Players - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+// encodeQueryStatRequest creates a basic or full stat request packet
+// https://wiki.vg/Query#Request_2
+func encodeQueryStatRequest(sessionID, challengeToken int32, full bool) ([]byte, error) {
+	buf := &bytes.Buffer{}
 
-			if err != nil {
-				return nil, err
-			}
+	// Magic - uint16
+	if _, err := buf.Write(magic); err != nil {
+		return nil, err
+	}
 
-			onlinePlayers, err := strconv.ParseUint(string(data[:len(data)-1]), 10, 64)
+	// Type - byte
+	if err := buf.WriteByte(0x00); err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	// Session ID - int32
+	if err := binary.Write(buf, binary.BigEndian, sessionID); err != nil {
+		return nil, err
+	}
 
-			response.OnlinePlayers = onlinePlayers
+	// Challenge Token - int32
+	if err := binary.Write(buf, binary.BigEndian, challengeToken); err != nil {
+		return nil, err
+	}
+
+	// Padding - bytes, the server sends a full stat response if the request is padded
+	if full {
+		if _, err := buf.Write([]byte{0x00, 0x00, 0x00, 0x00}); err != nil {
+			return nil, err
 		}
+	}
 
-		// Max Players - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+	return buf.Bytes(), nil
+}
 
-			if err != nil {
-				return nil, err
-			}
+// queryResponseMatcher accepts response datagrams of the packet type that echo the session ID
+func queryResponseMatcher(packetType byte, sessionID int32) func(data []byte) bool {
+	return func(data []byte) bool {
+		return len(data) >= 5 && data[0] == packetType && int32(binary.BigEndian.Uint32(data[1:5])) == sessionID
+	}
+}
 
-			maxPlayers, err := strconv.ParseUint(string(data[:len(data)-1]), 10, 64)
+// parseQueryHandshakeResponse parses the challenge token out of a handshake response packet
+// https://wiki.vg/Query#Response
+func parseQueryHandshakeResponse(data []byte) (int32, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
 
-			if err != nil {
-				return nil, err
-			}
+	// Type - byte
+	{
+		v, err := r.ReadByte()
 
-			response.MaxPlayers = maxPlayers
+		if err != nil {
+			return 0, err
 		}
 
-		// Host Port - uint16
-		{
-			var hostPort uint16
+		if v != 0x09 {
+			return 0, ErrUnexpectedResponse
+		}
+	}
 
-			if err := binary.Read(r, binary.LittleEndian, &hostPort); err != nil {
-				return nil, err
-			}
+	// Session ID - int32
+	{
+		var sessionID int32
 
-			response.HostPort = hostPort
+		if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
+			return 0, err
 		}
+	}
 
-		// Host IP - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+	// Challenge Token - null-terminated string
+	{
+		data, err := r.ReadBytes(0x00)
 
-			if err != nil {
-				return nil, err
-			}
+		if err != nil {
+			return 0, err
+		}
 
-			response.HostIP = string(data[:len(data)-1])
+		v, err := strconv.ParseInt(string(data[:len(data)-1]), 10, 32)
+
+		if err != nil {
+			return 0, err
 		}
-	}
 
-	return &response, nil
+		return int32(v), nil
+	}
 }
 
-// FullQuery runs a query on the server and returns the full information
-func FullQuery(host string, port uint16, options ...QueryOptions) (*FullQueryResponse, error) {
-	opts := parseQueryOptions(options...)
-
-	conn, err := net.DialTimeout("udp", fmt.Sprintf("%s:%d", host, port), opts.Timeout)
+// parseBasicQueryResponse parses a basic stat response packet
+// https://wiki.vg/Query#Response_2
+func parseBasicQueryResponse(data []byte) (*BasicQueryResponse, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
 
-	if err != nil {
-		return nil, err
-	}
+	response := BasicQueryResponse{}
 
-	defer conn.Close()
+	// Type - byte
+	{
+		v, err := r.ReadByte()
 
-	r := bufio.NewReader(conn)
+		if err != nil {
+			return nil, err
+		}
 
-	if err = conn.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
-		return nil, err
+		if v != 0x00 {
+			return nil, ErrUnexpectedResponse
+		}
 	}
 
-	// Handshake request packet
-	// https://wiki.vg/Query#Request
+	// Session ID - int32
 	{
-		buf := &bytes.Buffer{}
+		var sessionID int32
 
-		// Magic - uint16
-		if _, err := buf.Write(magic); err != nil {
+		if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
 			return nil, err
 		}
+	}
 
-		// Type - byte
-		if err := buf.WriteByte(0x09); err != nil {
-			return nil, err
-		}
+	// MOTD - null-terminated string
+	{
+		data, err := r.ReadBytes(0x00)
 
-		// Session ID - int32
-		if err := binary.Write(buf, binary.BigEndian, opts.SessionID&0x0F0F0F0F); err != nil {
+		if err != nil {
 			return nil, err
 		}
 
-		if _, err := io.Copy(conn, buf); err != nil {
+		description, err := ParseMOTD(decodeASCII(data[:len(data)-1]))
+
+		if err != nil {
 			return nil, err
 		}
-	}
 
-	var challengeToken int32
+		response.MOTD = *description
+	}
 
-	// Handshake response packet
-	// https://wiki.vg/Query#Response
+	// Game Type - null-terminated string
 	{
-		// Type - byte
-		{
-			v, err := r.ReadByte()
+		data, err := r.ReadBytes(0x00)
 
-			if err != nil {
-				return nil, err
-			}
-
-			if v != 0x09 {
-				return nil, ErrUnexpectedResponse
-			}
+		if err != nil {
+			return nil, err
 		}
 
-		// Session ID - int32
-		{
-			var sessionID int32
+		response.GameType = string(data[:len(data)-1])
+	}
 
-			if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
-				return nil, err
-			}
+	// Map - null-terminated string
+	{
+		data, err := r.ReadBytes(0x00)
 
-			if sessionID != opts.SessionID {
-				return nil, ErrUnexpectedResponse
-			}
+		if err != nil {
+			return nil, err
 		}
 
-		// Challenge Token - null-terminated string
-		{
-			data, err := r.ReadBytes(0x00)
+		response.Map = string(data[:len(data)-1])
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	// Online Players - null-terminated string
+	{
+		data, err := r.ReadBytes(0x00)
 
-			v, err := strconv.ParseInt(string(data[:len(data)-1]), 10, 32)
+		if err != nil {
+			return nil, err
+		}
 
-			if err != nil {
-				return nil, err
-			}
+		onlinePlayers, err := strconv.ParseUint(string(data[:len(data)-1]), 10, 64)
 
-			challengeToken = int32(v)
+		if err != nil {
+			return nil, err
 		}
+
+		response.OnlinePlayers = onlinePlayers
 	}
 
-	// Full stat request packet
-	// https://wiki.vg/Query#Request_2
+	// Max Players - null-terminated string
 	{
-		buf := &bytes.Buffer{}
+		data, err := r.ReadBytes(0x00)
 
-		// Magic - uint16
-		if _, err := buf.Write(magic); err != nil {
+		if err != nil {
 			return nil, err
 		}
 
-		// Type - byte
-		if err := buf.WriteByte(0x00); err != nil {
-			return nil, err
-		}
+		maxPlayers, err := strconv.ParseUint(string(data[:len(data)-1]), 10, 64)
 
-		// Session ID - int32
-		if err := binary.Write(buf, binary.BigEndian, opts.SessionID&0x0F0F0F0F); err != nil {
+		if err != nil {
 			return nil, err
 		}
 
-		// Challenge Token - int32
-		if err := binary.Write(buf, binary.BigEndian, challengeToken); err != nil {
-			return nil, err
-		}
+		response.MaxPlayers = maxPlayers
+	}
 
-		// Padding - bytes
-		if _, err := buf.Write([]byte{0x00, 0x00, 0x00, 0x00}); err != nil {
+	// Host Port - uint16
+	{
+		var hostPort uint16
+
+		if err := binary.Read(r, binary.LittleEndian, &hostPort); err != nil {
 			return nil, err
 		}
 
-		if _, err := io.Copy(conn, buf); err != nil {
+		response.HostPort = hostPort
+	}
+
+	// Host IP - null-terminated string
+	{
+		data, err := r.ReadBytes(0x00)
+
+		if err != nil {
 			return nil, err
 		}
+
+		response.HostIP = string(data[:len(data)-1])
 	}
 
+	return &response, nil
+}
+
+// parseFullQueryResponse parses a full stat response packet
+// https://wiki.vg/Query#Response_3
+func parseFullQueryResponse(data []byte) (*FullQueryResponse, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+
 	response := FullQueryResponse{
 		Data:    make(map[string]string),
 		Players: make([]string, 0),
 	}
 
-	// Full stat response packet
-	// https://wiki.vg/Query#Response_3
+	// Type - byte
 	{
-		// Type - byte
-		{
-			v, err := r.ReadByte()
+		v, err := r.ReadByte()
 
-			if err != nil {
-				return nil, err
-			}
+		if err != nil {
+			return nil, err
+		}
 
-			if v != 0x00 {
-				return nil, ErrUnexpectedResponse
-			}
+		if v != 0x00 {
+			return nil, ErrUnexpectedResponse
 		}
+	}
 
-		// Session ID - int16
-		{
-			var sessionID int32
+	// Session ID - int32
+	{
+		var sessionID int32
 
-			if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
-				return nil, err
-			}
+		if err := binary.Read(r, binary.BigEndian, &sessionID); err != nil {
+			return nil, err
+		}
+	}
 
-			if sessionID != opts.SessionID {
-				return nil, ErrUnexpectedResponse
-			}
+	// Padding - [11]byte
+	{
+		data := make([]byte, 11)
+
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
 		}
+	}
 
-		// Padding - [11]byte
-		{
-			data := make([]byte, 11)
+	// K, V section - null-terminated key,pair pair string
+	{
+		for {
+			data, err := r.ReadBytes(0x00)
 
-			if _, err := r.Read(data); err != nil {
+			if err != nil {
 				return nil, err
 			}
-		}
 
-		// K, V section - null-terminated key,pair pair string
-		{
-			for {
-				data, err := r.ReadBytes(0x00)
+			if len(data) < 2 {
+				break
+			}
 
-				if err != nil {
-					return nil, err
-				}
+			key := decodeASCII(data[:len(data)-1])
 
-				if len(data) < 2 {
-					break
-				}
+			data, err = r.ReadBytes(0x00)
 
-				key := decodeASCII(data[:len(data)-1])
+			if err != nil {
+				return nil, err
+			}
 
-				data, err = r.ReadBytes(0x00)
+			value := decodeASCII(data[:len(data)-1])
 
-				if err != nil {
-					return nil, err
-				}
+			response.Data[key] = value
+		}
+	}
 
-				value := decodeASCII(data[:len(data)-1])
+	// Padding - [10]byte
+	{
+		data := make([]byte, 10)
 
-				response.Data[key] = value
-			}
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
 		}
+	}
 
-		// Padding - [10]byte
-		{
-			data := make([]byte, 10)
+	// Players section - null-terminated key,value pair string
+	{
+		for {
+			data, err := r.ReadBytes(0x00)
 
-			if _, err := r.Read(data); err != nil {
+			if err != nil {
 				return nil, err
 			}
-		}
-
-		// Players section - null-terminated key,value pair string
-		{
-			for {
-				data, err := r.ReadBytes(0x00)
-
-				if err != nil {
-					return nil, err
-				}
-
-				if len(data) < 2 {
-					break
-				}
 
-				response.Players = append(response.Players, string(data[:len(data)-1]))
+			if len(data) < 2 {
+				break
 			}
+
+			response.Players = append(response.Players, string(data[:len(data)-1]))
 		}
 	}
 
@@ -551,12 +548,16 @@ func parseQueryOptions(opts ...QueryOptions) QueryOptions {
 	if len(opts) < 1 {
 		options := QueryOptions(defaultQueryOptions)
 
-		sessionID += 1
-
-		options.SessionID = sessionID
+		options.SessionID = querySessionID(atomic.AddInt32(&sessionID, 1))
 
 		return options
 	}
 
 	return opts[0]
 }
+
+// querySessionID spreads the lower 16 bits of the counter over the bits of a session ID that are not masked
+// out by the server, so that consecutive counters produce distinct session IDs
+func querySessionID(counter int32) int32 {
+	return (counter & 0x0F) | (counter>>4&0x0F)<<8 | (counter>>8&0x0F)<<16 | (counter>>12&0x0F)<<24
+}
@@ -0,0 +1,482 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	defaultQueryClientOptions = QueryClientOptions{
+		Timeout:           time.Second * 5,
+		MaxAttempts:       3,
+		RetryInterval:     time.Millisecond * 500,
+		ChallengeLifetime: time.Second * 25,
+		ListenAddress:     ":0",
+	}
+)
+
+type QueryClientOptions struct {
+	Timeout           time.Duration
+	MaxAttempts       int
+	RetryInterval     time.Duration
+	ChallengeLifetime time.Duration
+	ListenAddress     string
+}
+
+// QueryClient runs queries against many servers over a single UDP socket and is safe for concurrent use.
+// Challenge tokens are cached per server for the challenge lifetime, which should be a little shorter than
+// the 30 seconds a server accepts them for, so the handshake is only repeated once a token has gone stale.
+// A server keeps a single challenge for each client address, and replies to stat requests with the session ID
+// of the handshake that created it, so the session ID is cached along with the token and only one handshake
+// is made with a server at a time.
+type QueryClient struct {
+	conn       net.PacketConn
+	options    QueryClientOptions
+	sessionID  int32
+	pending    map[queryPendingKey][]chan []byte
+	tokens     map[string]queryChallenge
+	handshakes map[string]*queryHandshakeCall
+	lock       sync.Mutex
+	done       chan struct{}
+	once       sync.Once
+	wg         sync.WaitGroup
+}
+
+// queryPendingKey identifies the responses a request is waiting for. Queries to the same server share a
+// session ID, but basic and full stat responses can be told apart, and any response of the right kind answers
+// any of the requests waiting for it.
+type queryPendingKey struct {
+	addr       string
+	sessionID  int32
+	packetType byte
+	full       bool
+}
+
+type queryChallenge struct {
+	token     int32
+	sessionID int32
+	expires   time.Time
+}
+
+// queryHandshakeCall is a handshake in progress, which every query to the server waits for
+type queryHandshakeCall struct {
+	done      chan struct{}
+	challenge queryChallenge
+	err       error
+}
+
+// NewQueryClient opens the UDP socket used for queries and starts receiving responses
+func NewQueryClient(options ...QueryClientOptions) (*QueryClient, error) {
+	opts := parseQueryClientOptions(options...)
+
+	conn, err := net.ListenPacket("udp", opts.ListenAddress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := &QueryClient{
+		conn:       conn,
+		options:    opts,
+		pending:    make(map[queryPendingKey][]chan []byte),
+		tokens:     make(map[string]queryChallenge),
+		handshakes: make(map[string]*queryHandshakeCall),
+		done:       make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+
+	go c.receive()
+
+	return c, nil
+}
+
+// BasicQuery runs a query on the server and returns basic information
+func (c *QueryClient) BasicQuery(host string, port uint16) (*BasicQueryResponse, error) {
+	data, attempts, err := c.stat(host, port, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := parseBasicQueryResponse(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Attempts = attempts
+
+	return response, nil
+}
+
+// FullQuery runs a query on the server and returns the full information
+func (c *QueryClient) FullQuery(host string, port uint16) (*FullQueryResponse, error) {
+	data, attempts, err := c.stat(host, port, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := parseFullQueryResponse(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Attempts = attempts
+
+	return response, nil
+}
+
+// Close closes the socket, queries that are still running return an error
+func (c *QueryClient) Close() error {
+	err := ErrServerClosed
+
+	c.once.Do(func() {
+		close(c.done)
+
+		err = c.conn.Close()
+
+		c.wg.Wait()
+	})
+
+	return err
+}
+
+func (c *QueryClient) stat(host string, port uint16, full bool) ([]byte, int, error) {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deadline := time.Now().Add(c.options.Timeout)
+	attempts := 0
+
+	challenge, ok := c.cachedChallenge(addr)
+
+	if ok {
+		request, err := encodeQueryStatRequest(challenge.sessionID, challenge.token, full)
+
+		if err != nil {
+			return nil, attempts, err
+		}
+
+		// A server silently drops requests with an expired token, so a cached token only gets a single
+		// attempt before it is replaced with a new one
+		cachedDeadline := time.Now().Add(c.options.RetryInterval)
+
+		if cachedDeadline.After(deadline) {
+			cachedDeadline = deadline
+		}
+
+		data, n, err := c.exchange(addr, request, queryPendingKey{sessionID: challenge.sessionID, packetType: 0x00, full: full}, cachedDeadline, 1)
+
+		attempts += n
+
+		if err == nil {
+			return data, attempts, nil
+		}
+
+		if err != ErrTimeout {
+			return nil, attempts, err
+		}
+
+		c.invalidateChallenge(addr, challenge)
+	}
+
+	challenge, n, err := c.handshake(addr, deadline, challenge)
+
+	attempts += n
+
+	if err != nil {
+		return nil, attempts, err
+	}
+
+	request, err := encodeQueryStatRequest(challenge.sessionID, challenge.token, full)
+
+	if err != nil {
+		return nil, attempts, err
+	}
+
+	data, n, err := c.exchange(addr, request, queryPendingKey{sessionID: challenge.sessionID, packetType: 0x00, full: full}, deadline, c.options.MaxAttempts)
+
+	return data, attempts + n, err
+}
+
+// handshake requests a new challenge from the server and caches it. If a handshake with the server is already
+// in progress its challenge is used instead, as a second handshake would replace the challenge of the first,
+// and so is a challenge that was cached since the query found the stale one. The attempts are only counted by
+// the query that made the handshake.
+func (c *QueryClient) handshake(addr *net.UDPAddr, deadline time.Time, stale queryChallenge) (queryChallenge, int, error) {
+	c.lock.Lock()
+
+	if challenge, ok := c.tokens[addr.String()]; ok && challenge != stale && time.Now().Before(challenge.expires) {
+		c.lock.Unlock()
+
+		return challenge, 0, nil
+	}
+
+	if call, ok := c.handshakes[addr.String()]; ok {
+		c.lock.Unlock()
+
+		select {
+		case <-call.done:
+			return call.challenge, 0, call.err
+		case <-c.done:
+			return queryChallenge{}, 0, ErrServerClosed
+		}
+	}
+
+	call := &queryHandshakeCall{
+		done: make(chan struct{}),
+	}
+
+	c.handshakes[addr.String()] = call
+
+	c.lock.Unlock()
+
+	defer (func() {
+		c.lock.Lock()
+
+		if call.err == nil {
+			c.tokens[addr.String()] = call.challenge
+		}
+
+		delete(c.handshakes, addr.String())
+
+		c.lock.Unlock()
+
+		close(call.done)
+	})()
+
+	sessionID := querySessionID(atomic.AddInt32(&c.sessionID, 1))
+
+	request, err := encodeQueryHandshakeRequest(sessionID)
+
+	if err != nil {
+		call.err = err
+
+		return call.challenge, 0, err
+	}
+
+	data, attempts, err := c.exchange(addr, request, queryPendingKey{sessionID: sessionID, packetType: 0x09}, deadline, c.options.MaxAttempts)
+
+	if err != nil {
+		call.err = err
+
+		return call.challenge, attempts, err
+	}
+
+	token, err := parseQueryHandshakeResponse(data)
+
+	if err != nil {
+		call.err = err
+
+		return call.challenge, attempts, err
+	}
+
+	call.challenge = queryChallenge{
+		token:     token,
+		sessionID: sessionID,
+		expires:   time.Now().Add(c.options.ChallengeLifetime),
+	}
+
+	return call.challenge, attempts, nil
+}
+
+// exchange sends the request to the address and waits for a response matching the key to be received,
+// retransmitting the request with backoff until the deadline
+func (c *QueryClient) exchange(addr *net.UDPAddr, request []byte, key queryPendingKey, deadline time.Time, maxAttempts int) ([]byte, int, error) {
+	key.addr = addr.String()
+
+	response := make(chan []byte, 1)
+
+	c.lock.Lock()
+	c.pending[key] = append(c.pending[key], response)
+	c.lock.Unlock()
+
+	defer c.removePending(key, response)
+
+	attempts := 0
+
+	for {
+		if _, err := c.conn.WriteTo(request, addr); err != nil {
+			return nil, attempts, err
+		}
+
+		attempts++
+
+		wait := deadline
+
+		if attempts < maxAttempts && c.options.RetryInterval > 0 {
+			if next := time.Now().Add(retryBackoff(c.options.RetryInterval, attempts)); next.Before(deadline) {
+				wait = next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(wait))
+
+		select {
+		case data := <-response:
+			{
+				timer.Stop()
+
+				return data, attempts, nil
+			}
+		case <-timer.C:
+			{
+				if wait.Before(deadline) {
+					continue
+				}
+
+				return nil, attempts, ErrTimeout
+			}
+		case <-c.done:
+			{
+				timer.Stop()
+
+				return nil, attempts, ErrServerClosed
+			}
+		}
+	}
+}
+
+// removePending stops the request from waiting for a response, if it has not already received one
+func (c *QueryClient) removePending(key queryPendingKey, response chan []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	waiting := c.pending[key]
+
+	for i, w := range waiting {
+		if w == response {
+			waiting = append(waiting[:i:i], waiting[i+1:]...)
+
+			break
+		}
+	}
+
+	if len(waiting) < 1 {
+		delete(c.pending, key)
+	} else {
+		c.pending[key] = waiting
+	}
+}
+
+func (c *QueryClient) receive() {
+	defer c.wg.Done()
+
+	data := make([]byte, 1<<16)
+
+	for {
+		n, addr, err := c.conn.ReadFrom(data)
+
+		if err != nil {
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+
+			time.Sleep(time.Millisecond * 10)
+
+			continue
+		}
+
+		if n < 5 {
+			continue
+		}
+
+		key := queryPendingKey{
+			addr:       addr.String(),
+			sessionID:  int32(binary.BigEndian.Uint32(data[1:5])),
+			packetType: data[0],
+			full:       data[0] == 0x00 && bytes.HasPrefix(data[5:n], []byte("splitnum\x00")),
+		}
+
+		result := make([]byte, n)
+
+		copy(result, data[:n])
+
+		// The response goes to the request that has been waiting the longest, which stops waiting for more
+		c.lock.Lock()
+
+		if waiting := c.pending[key]; len(waiting) > 0 {
+			waiting[0] <- result
+
+			if len(waiting) > 1 {
+				c.pending[key] = waiting[1:]
+			} else {
+				delete(c.pending, key)
+			}
+		}
+
+		c.lock.Unlock()
+	}
+}
+
+func (c *QueryClient) cachedChallenge(addr *net.UDPAddr) (queryChallenge, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	challenge, ok := c.tokens[addr.String()]
+
+	if !ok {
+		return queryChallenge{}, false
+	}
+
+	if time.Now().After(challenge.expires) {
+		delete(c.tokens, addr.String())
+
+		return queryChallenge{}, false
+	}
+
+	return challenge, true
+}
+
+// invalidateChallenge removes the challenge from the cache, unless it has already been replaced by a newer one
+func (c *QueryClient) invalidateChallenge(addr *net.UDPAddr, challenge queryChallenge) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.tokens[addr.String()] == challenge {
+		delete(c.tokens, addr.String())
+	}
+}
+
+func parseQueryClientOptions(opts ...QueryClientOptions) QueryClientOptions {
+	if len(opts) < 1 {
+		return defaultQueryClientOptions
+	}
+
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultQueryClientOptions.Timeout
+	}
+
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = defaultQueryClientOptions.MaxAttempts
+	}
+
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = defaultQueryClientOptions.RetryInterval
+	}
+
+	if options.ChallengeLifetime <= 0 {
+		options.ChallengeLifetime = defaultQueryClientOptions.ChallengeLifetime
+	}
+
+	if len(options.ListenAddress) < 1 {
+		options.ListenAddress = defaultQueryClientOptions.ListenAddress
+	}
+
+	return options
+}
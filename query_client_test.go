@@ -0,0 +1,229 @@
+package mcstatus_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+// testQueryTokenServer issues a challenge for each client address, and like a vanilla server it replaces the
+// challenge on every handshake and replies to stat requests with the session ID sent in the handshake
+type testQueryTokenServer struct {
+	handshakes int32
+	challenges map[string][2]int32
+	lock       sync.Mutex
+}
+
+// expire forgets every challenge, like a server does once they are 30 seconds old
+func (s *testQueryTokenServer) expire() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.challenges = make(map[string][2]int32)
+}
+
+// serveTestQueryTokens answers query requests and ignores stat requests that do not use the current challenge
+// token of the client
+func serveTestQueryTokens(conn net.PacketConn, server *testQueryTokenServer) {
+	data := make([]byte, 1500)
+
+	for {
+		n, addr, err := conn.ReadFrom(data)
+
+		if err != nil {
+			return
+		}
+
+		if n < 7 || data[0] != 0xFE || data[1] != 0xFD {
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		buf.WriteByte(data[2])
+
+		server.lock.Lock()
+		challenge, ok := server.challenges[addr.String()]
+
+		if data[2] == 0x09 {
+			handshakes := atomic.AddInt32(&server.handshakes, 1)
+
+			challenge = [2]int32{int32(binary.BigEndian.Uint32(data[3:7])), 9513307 + handshakes}
+			server.challenges[addr.String()] = challenge
+		}
+
+		server.lock.Unlock()
+
+		if data[2] == 0x09 {
+			buf.Write(data[3:7])
+			buf.WriteString(strconv.Itoa(int(challenge[1])) + "\x00")
+		} else {
+			if !ok || n < 11 || int32(binary.BigEndian.Uint32(data[7:11])) != challenge[1] {
+				continue
+			}
+
+			binary.Write(buf, binary.BigEndian, challenge[0])
+
+			if n == 15 {
+				buf.WriteString("splitnum\x00\x80\x00")
+				buf.WriteString("hostname\x00A Minecraft Server\x00numplayers\x002\x00maxplayers\x0020\x00\x00")
+				buf.WriteString("\x01player_\x00\x00")
+				buf.WriteString("Notch\x00jeb_\x00\x00")
+			} else {
+				buf.WriteString("A Minecraft Server\x00SMP\x00world\x002\x0020\x00")
+				binary.Write(buf, binary.LittleEndian, uint16(25565))
+				buf.WriteString("127.0.0.1\x00")
+			}
+		}
+
+		conn.WriteTo(buf.Bytes(), addr)
+	}
+}
+
+func TestQueryClient(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	server := &testQueryTokenServer{challenges: make(map[string][2]int32)}
+
+	go serveTestQueryTokens(conn, server)
+
+	client, err := mcstatus.NewQueryClient(mcstatus.QueryClientOptions{
+		Timeout:           time.Second * 5,
+		MaxAttempts:       3,
+		RetryInterval:     time.Millisecond * 100,
+		ChallengeLifetime: time.Second * 25,
+		ListenAddress:     "127.0.0.1:0",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	port := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+
+	query := func() {
+		wg := &sync.WaitGroup{}
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go (func(full bool) {
+				defer wg.Done()
+
+				if full {
+					response, err := client.FullQuery("127.0.0.1", port)
+
+					if err != nil {
+						t.Error(err)
+					} else if response.Data["numplayers"] != "2" || len(response.Players) != 2 {
+						t.Errorf("unexpected response: %+v", response)
+					}
+
+					return
+				}
+
+				response, err := client.BasicQuery("127.0.0.1", port)
+
+				if err != nil {
+					t.Error(err)
+				} else if response.OnlinePlayers != 2 || response.MaxPlayers != 20 {
+					t.Errorf("unexpected response: %+v", response)
+				}
+			})(i%2 == 0)
+		}
+
+		wg.Wait()
+	}
+
+	// Queries that start at the same time share a single handshake
+	query()
+
+	if handshakes := atomic.LoadInt32(&server.handshakes); handshakes != 1 {
+		t.Fatalf("expected a single handshake, got %d", handshakes)
+	}
+
+	// Later queries use the cached challenge straight away
+	start := time.Now()
+
+	query()
+
+	if handshakes := atomic.LoadInt32(&server.handshakes); handshakes != 1 {
+		t.Fatalf("expected the challenge token to be shared between queries, got %d handshakes", handshakes)
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Millisecond*100 {
+		t.Fatalf("queries with a cached challenge took %s", elapsed)
+	}
+
+	// The server no longer accepts the cached token, so the client has to request a new one
+	server.expire()
+
+	response, err := client.FullQuery("127.0.0.1", port)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Data["numplayers"] != "2" || len(response.Players) != 2 || response.Attempts != 3 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	if handshakes := atomic.LoadInt32(&server.handshakes); handshakes != 2 {
+		t.Fatalf("expected a new challenge token to be requested, got %d handshakes", handshakes)
+	}
+}
+
+func TestQueryClientDefaultOptions(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	server := &testQueryTokenServer{challenges: make(map[string][2]int32)}
+
+	go serveTestQueryTokens(conn, server)
+
+	// Only the listen address is set, every other option uses its default
+	client, err := mcstatus.NewQueryClient(mcstatus.QueryClientOptions{ListenAddress: "127.0.0.1:0"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	port := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+
+	for i := 0; i < 3; i++ {
+		response, err := client.BasicQuery("127.0.0.1", port)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.OnlinePlayers != 2 {
+			t.Fatalf("unexpected response: %+v", response)
+		}
+	}
+
+	if handshakes := atomic.LoadInt32(&server.handshakes); handshakes != 1 {
+		t.Fatalf("expected the challenge token to be cached, got %d handshakes", handshakes)
+	}
+}
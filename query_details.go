@@ -0,0 +1,176 @@
+package mcstatus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FullQueryDetails is a typed view of the key-value section of a full query response
+type FullQueryDetails struct {
+	MOTD          MOTD
+	GameType      string
+	GameID        string
+	Version       string
+	Software      string
+	Plugins       []QueryPlugin
+	OnlinePlayers uint64
+	MaxPlayers    uint64
+	HostPort      uint16
+	HostIP        string
+	Map           string
+	Players       []string
+	Extra         map[string]string
+}
+
+// QueryPlugin is a single plugin from the plugins value of a full query response, the version is empty if the
+// server did not send one
+type QueryPlugin struct {
+	Name    string
+	Version string
+}
+
+func (p QueryPlugin) String() string {
+	if len(p.Version) < 1 {
+		return p.Name
+	}
+
+	return p.Name + " " + p.Version
+}
+
+func (d FullQueryDetails) String() string {
+	plugins := make([]string, 0, len(d.Plugins))
+
+	for _, plugin := range d.Plugins {
+		plugins = append(plugins, plugin.String())
+	}
+
+	return fmt.Sprintf(
+		"Host: %s\nPort: %d\nVersion: %s\nSoftware: %s\nPlugins: %s\nPlayers: %d/%d\nMap: %s\nMOTD: %s",
+		d.HostIP,
+		d.HostPort,
+		d.Version,
+		d.Software,
+		strings.Join(plugins, ", "),
+		d.OnlinePlayers,
+		d.MaxPlayers,
+		d.Map,
+		d.MOTD,
+	)
+}
+
+// Details parses the standard keys of the response into typed fields, any keys that are not known are kept in Extra
+func (r FullQueryResponse) Details() (*FullQueryDetails, error) {
+	details := &FullQueryDetails{
+		Plugins: make([]QueryPlugin, 0),
+		Players: r.Players,
+		Extra:   make(map[string]string),
+	}
+
+	for key, value := range r.Data {
+		switch key {
+		case "hostname":
+			{
+				motd, err := ParseMOTD(value)
+
+				if err != nil {
+					return nil, err
+				}
+
+				details.MOTD = *motd
+			}
+		case "gametype":
+			details.GameType = value
+		case "game_id":
+			details.GameID = value
+		case "version":
+			details.Version = value
+		case "plugins":
+			details.Software, details.Plugins = parseQueryPlugins(value)
+		case "map":
+			details.Map = value
+		case "numplayers":
+			{
+				onlinePlayers, err := strconv.ParseUint(value, 10, 64)
+
+				if err != nil {
+					return nil, err
+				}
+
+				details.OnlinePlayers = onlinePlayers
+			}
+		case "maxplayers":
+			{
+				maxPlayers, err := strconv.ParseUint(value, 10, 64)
+
+				if err != nil {
+					return nil, err
+				}
+
+				details.MaxPlayers = maxPlayers
+			}
+		case "hostport":
+			{
+				hostPort, err := strconv.ParseUint(value, 10, 16)
+
+				if err != nil {
+					return nil, err
+				}
+
+				details.HostPort = uint16(hostPort)
+			}
+		case "hostip":
+			details.HostIP = value
+		default:
+			details.Extra[key] = value
+		}
+	}
+
+	return details, nil
+}
+
+// parseQueryPlugins splits the plugins value of a full query response, which is formatted as
+// "Software: Plugin 1.0; Other Plugin 2.0", into the server software and the list of plugins
+func parseQueryPlugins(value string) (string, []QueryPlugin) {
+	plugins := make([]QueryPlugin, 0)
+
+	index := strings.Index(value, ":")
+
+	if index < 0 {
+		return strings.TrimSpace(value), plugins
+	}
+
+	software := strings.TrimSpace(value[:index])
+
+	for _, entry := range strings.Split(value[index+1:], ";") {
+		entry = strings.TrimSpace(entry)
+
+		if len(entry) < 1 {
+			continue
+		}
+
+		plugin := QueryPlugin{
+			Name: entry,
+		}
+
+		// The version is the last word of the entry, plugin names themselves may contain spaces, so the last
+		// word is only a version if it looks like one
+		if space := strings.LastIndex(entry, " "); space > 0 && isQueryPluginVersion(entry[space+1:]) {
+			plugin.Name = strings.TrimSpace(entry[:space])
+			plugin.Version = entry[space+1:]
+		}
+
+		plugins = append(plugins, plugin)
+	}
+
+	return software, plugins
+}
+
+// isQueryPluginVersion reports whether the word is a version, which starts with a digit or a v and a digit
+func isQueryPluginVersion(word string) bool {
+	if len(word) > 1 && (word[0] == 'v' || word[0] == 'V') {
+		word = word[1:]
+	}
+
+	return len(word) > 0 && word[0] >= '0' && word[0] <= '9'
+}
@@ -0,0 +1,82 @@
+package mcstatus_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestFullQueryDetails(t *testing.T) {
+	response := mcstatus.FullQueryResponse{
+		Data: map[string]string{
+			"hostname":   "§aA Minecraft Server",
+			"gametype":   "SMP",
+			"game_id":    "MINECRAFT",
+			"version":    "1.20.4",
+			"plugins":    "Paper on Bukkit 1.20.4: LuckPerms 5.4; EssentialsX 2.20; World Edit 7.2; My Plugin; Vault v1.7",
+			"map":        "world",
+			"numplayers": "2",
+			"maxplayers": "20",
+			"hostport":   "25565",
+			"hostip":     "127.0.0.1",
+			"whitelist":  "off",
+		},
+		Players: []string{"Notch", "jeb_"},
+	}
+
+	details, err := response.Details()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if details.MOTD.Clean() != "A Minecraft Server" || details.GameID != "MINECRAFT" || details.OnlinePlayers != 2 || details.MaxPlayers != 20 || details.HostPort != 25565 {
+		t.Fatalf("unexpected details: %+v", details)
+	}
+
+	if details.Software != "Paper on Bukkit 1.20.4" {
+		t.Fatalf("unexpected software: %s", details.Software)
+	}
+
+	expected := []mcstatus.QueryPlugin{
+		{Name: "LuckPerms", Version: "5.4"},
+		{Name: "EssentialsX", Version: "2.20"},
+		{Name: "World Edit", Version: "7.2"},
+		{Name: "My Plugin"},
+		{Name: "Vault", Version: "v1.7"},
+	}
+
+	if !reflect.DeepEqual(details.Plugins, expected) {
+		t.Fatalf("unexpected plugins: %+v", details.Plugins)
+	}
+
+	if details.Extra["whitelist"] != "off" || len(details.Extra) != 1 {
+		t.Fatalf("unexpected extra keys: %+v", details.Extra)
+	}
+}
+
+func TestFullQueryDetailsVanilla(t *testing.T) {
+	response := mcstatus.FullQueryResponse{
+		Data: map[string]string{
+			"plugins":    "",
+			"numplayers": "abc",
+		},
+	}
+
+	if _, err := response.Details(); err == nil {
+		t.Fatal("expected an error for a malformed player count")
+	}
+
+	response.Data["numplayers"] = "0"
+
+	details, err := response.Details()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if details.Software != "" || len(details.Plugins) != 0 {
+		t.Fatalf("unexpected plugins: %+v", details)
+	}
+}
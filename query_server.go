@@ -0,0 +1,402 @@
+package mcstatus
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	defaultQueryServerOptions = QueryServerOptions{
+		ChallengeLifetime: time.Second * 30,
+		MaxChallenges:     1 << 16,
+		RateLimit:         20,
+		RateBurst:         40,
+	}
+	// errTooManyChallenges means a challenge was not issued because the max challenges have been issued
+	errTooManyChallenges = errors.New("query server has issued the max challenges")
+	queryServerKeyOrder  = []string{"hostname", "gametype", "game_id", "version", "plugins", "map", "numplayers", "maxplayers", "hostport", "hostip"}
+)
+
+// QueryDataProvider returns the data that is sent in response to a stat request from the address. Basic stat
+// responses are built from the hostname, gametype, map, numplayers, maxplayers, hostport and hostip keys.
+type QueryDataProvider func(addr net.Addr) (*FullQueryResponse, error)
+
+type QueryServerOptions struct {
+	ChallengeLifetime time.Duration
+	MaxChallenges     int
+	RateLimit         float64
+	RateBurst         int
+}
+
+// QueryServer answers query requests the same way a vanilla server does. Every client address is issued its
+// own challenge token, and stat requests are only answered for a valid token, which keeps the server from
+// being used to reflect traffic at a spoofed address. Requests over the rate limit of an IP are dropped, a
+// rate limit of 0 turns rate limiting off. Handshakes from new addresses are dropped while the max challenges
+// have been issued and not yet expired, so spoofed handshakes cannot use up the memory of the server.
+type QueryServer struct {
+	provider QueryDataProvider
+	options  QueryServerOptions
+	limiter  *rateLimiter
+	tokens   map[string]queryChallenge
+	lock     sync.Mutex
+	server   udpServer
+}
+
+// NewQueryServer creates a new query server that answers stat requests with the data from the provider
+func NewQueryServer(provider QueryDataProvider, options ...QueryServerOptions) *QueryServer {
+	opts := parseQueryServerOptions(options...)
+
+	return &QueryServer{
+		provider: provider,
+		options:  opts,
+		limiter: &rateLimiter{
+			rate:  opts.RateLimit,
+			burst: float64(opts.RateBurst),
+		},
+		tokens: make(map[string]queryChallenge),
+	}
+}
+
+// ListenAndServe listens on the UDP address and answers query requests
+func (s *QueryServer) ListenAndServe(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(conn)
+}
+
+// Serve answers query requests received on the connection until the server is closed
+func (s *QueryServer) Serve(conn net.PacketConn) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go s.pruneChallenges(done)
+
+	return s.server.serve(conn, s.handle)
+}
+
+// Close stops answering query requests and closes the connection
+func (s *QueryServer) Close() error {
+	return s.server.close()
+}
+
+func (s *QueryServer) handle(conn net.PacketConn, data []byte, addr net.Addr) {
+	if len(data) < 7 || data[0] != magic[0] || data[1] != magic[1] {
+		return
+	}
+
+	if !s.limiter.allow(addrIP(addr)) {
+		return
+	}
+
+	packetType := data[2]
+	sessionID := int32(binary.BigEndian.Uint32(data[3:7]))
+
+	var response []byte
+	var err error
+
+	switch packetType {
+	case 0x09:
+		{
+			var token int32
+
+			if token, err = s.issueChallenge(addr); err != nil {
+				return
+			}
+
+			response, err = encodeQueryHandshakeResponse(sessionID, token)
+		}
+	case 0x00:
+		{
+			// Basic stat requests carry only the challenge token, full stat requests are padded with four more bytes
+			if len(data) != 11 && len(data) != 15 {
+				return
+			}
+
+			if !s.validChallenge(addr, int32(binary.BigEndian.Uint32(data[7:11]))) {
+				return
+			}
+
+			var result *FullQueryResponse
+
+			if result, err = s.provider(addr); err != nil || result == nil {
+				return
+			}
+
+			if len(data) == 15 {
+				response, err = encodeFullQueryResponse(sessionID, result)
+			} else {
+				response, err = encodeBasicQueryResponse(sessionID, result)
+			}
+		}
+	default:
+		return
+	}
+
+	if err != nil || response == nil {
+		return
+	}
+
+	conn.WriteTo(response, addr)
+}
+
+// issueChallenge returns the challenge token of the address, creating a new one if it has none or it has expired
+func (s *QueryServer) issueChallenge(addr net.Addr) (int32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+
+	challenge, ok := s.tokens[addr.String()]
+
+	if ok && now.Before(challenge.expires) {
+		return challenge.token, nil
+	}
+
+	if !ok && len(s.tokens) >= s.options.MaxChallenges {
+		return 0, errTooManyChallenges
+	}
+
+	var token int32
+
+	if err := binary.Read(rand.Reader, binary.BigEndian, &token); err != nil {
+		return 0, err
+	}
+
+	// Clients parse the token as a signed decimal number, a positive value avoids clients that do not expect a sign
+	token &= 0x7FFFFFFF
+
+	s.tokens[addr.String()] = queryChallenge{
+		token:   token,
+		expires: now.Add(s.options.ChallengeLifetime),
+	}
+
+	return token, nil
+}
+
+// pruneChallenges removes expired challenges until the done channel is closed
+func (s *QueryServer) pruneChallenges(done chan struct{}) {
+	ticker := time.NewTicker(s.options.ChallengeLifetime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			{
+				s.lock.Lock()
+
+				for key, challenge := range s.tokens {
+					if now.After(challenge.expires) {
+						delete(s.tokens, key)
+					}
+				}
+
+				s.lock.Unlock()
+			}
+		}
+	}
+}
+
+func (s *QueryServer) validChallenge(addr net.Addr, token int32) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	challenge, ok := s.tokens[addr.String()]
+
+	return ok && challenge.token == token && time.Now().Before(challenge.expires)
+}
+
+// encodeQueryHandshakeResponse creates a handshake response packet
+// https://wiki.vg/Query#Response
+func encodeQueryHandshakeResponse(sessionID, token int32) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// Type - byte
+	if err := buf.WriteByte(0x09); err != nil {
+		return nil, err
+	}
+
+	// Session ID - int32
+	if err := binary.Write(buf, binary.BigEndian, sessionID); err != nil {
+		return nil, err
+	}
+
+	// Challenge Token - null-terminated string
+	if _, err := buf.WriteString(strconv.FormatInt(int64(token), 10) + "\x00"); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeBasicQueryResponse creates a basic stat response packet
+// https://wiki.vg/Query#Response_2
+func encodeBasicQueryResponse(sessionID int32, result *FullQueryResponse) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// Type - byte
+	if err := buf.WriteByte(0x00); err != nil {
+		return nil, err
+	}
+
+	// Session ID - int32
+	if err := binary.Write(buf, binary.BigEndian, sessionID); err != nil {
+		return nil, err
+	}
+
+	// MOTD, Game Type, Map, Online Players, Max Players - null-terminated strings
+	for _, key := range []string{"hostname", "gametype", "map", "numplayers", "maxplayers"} {
+		value, ok := result.Data[key]
+
+		if !ok && (key == "numplayers" || key == "maxplayers") {
+			value = "0"
+		}
+
+		if _, err := buf.WriteString(value + "\x00"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Host Port - uint16
+	{
+		hostPort, _ := strconv.ParseUint(result.Data["hostport"], 10, 16)
+
+		if err := binary.Write(buf, binary.LittleEndian, uint16(hostPort)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Host IP - null-terminated string
+	if _, err := buf.WriteString(result.Data["hostip"] + "\x00"); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeFullQueryResponse creates a full stat response packet, the standard keys are written in the same order
+// as a vanilla server followed by any other keys in alphabetical order
+// https://wiki.vg/Query#Response_3
+func encodeFullQueryResponse(sessionID int32, result *FullQueryResponse) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// Type - byte
+	if err := buf.WriteByte(0x00); err != nil {
+		return nil, err
+	}
+
+	// Session ID - int32
+	if err := binary.Write(buf, binary.BigEndian, sessionID); err != nil {
+		return nil, err
+	}
+
+	// Padding - [11]byte
+	if _, err := buf.WriteString("splitnum\x00\x80\x00"); err != nil {
+		return nil, err
+	}
+
+	// K, V section - null-terminated key,pair pair string
+	{
+		keys := make([]string, 0, len(result.Data))
+		known := make(map[string]struct{})
+
+		for _, key := range queryServerKeyOrder {
+			known[key] = struct{}{}
+
+			if _, ok := result.Data[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+
+		extra := make([]string, 0)
+
+		for key := range result.Data {
+			if _, ok := known[key]; !ok {
+				extra = append(extra, key)
+			}
+		}
+
+		sort.Strings(extra)
+
+		for _, key := range append(keys, extra...) {
+			if _, err := buf.WriteString(key + "\x00" + result.Data[key] + "\x00"); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := buf.WriteByte(0x00); err != nil {
+			return nil, err
+		}
+	}
+
+	// Padding - [10]byte
+	if _, err := buf.WriteString("\x01player_\x00\x00"); err != nil {
+		return nil, err
+	}
+
+	// Players section - null-terminated key,value pair string
+	{
+		for _, player := range result.Players {
+			if _, err := buf.WriteString(player + "\x00"); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := buf.WriteByte(0x00); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// addrIP returns the IP of the address without the port, which is used to group requests from the same host
+func addrIP(addr net.Addr) string {
+	switch v := addr.(type) {
+	case *net.UDPAddr:
+		return v.IP.String()
+	case *net.TCPAddr:
+		return v.IP.String()
+	}
+
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+
+	return addr.String()
+}
+
+func parseQueryServerOptions(opts ...QueryServerOptions) QueryServerOptions {
+	if len(opts) < 1 {
+		return defaultQueryServerOptions
+	}
+
+	options := opts[0]
+
+	if options.ChallengeLifetime <= 0 {
+		options.ChallengeLifetime = defaultQueryServerOptions.ChallengeLifetime
+	}
+
+	if options.MaxChallenges < 1 {
+		options.MaxChallenges = defaultQueryServerOptions.MaxChallenges
+	}
+
+	// A rate limit without a burst would drop every request
+	if options.RateLimit > 0 && options.RateBurst < 1 {
+		options.RateBurst = defaultQueryServerOptions.RateBurst
+	}
+
+	return options
+}
@@ -0,0 +1,183 @@
+package mcstatus_test
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func startTestQueryServer(t *testing.T, options mcstatus.QueryServerOptions) uint16 {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewQueryServer(func(addr net.Addr) (*mcstatus.FullQueryResponse, error) {
+		return &mcstatus.FullQueryResponse{
+			Data: map[string]string{
+				"hostname":   "A Minecraft Server",
+				"gametype":   "SMP",
+				"game_id":    "MINECRAFT",
+				"version":    "1.20.4",
+				"plugins":    "Paper on Bukkit 1.20.4: LuckPerms 5.4",
+				"map":        "world",
+				"numplayers": "2",
+				"maxplayers": "20",
+				"hostport":   "25565",
+				"hostip":     "127.0.0.1",
+			},
+			Players: []string{"Notch", "jeb_"},
+		}, nil
+	}, options)
+
+	go server.Serve(conn)
+
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func TestQueryServer(t *testing.T) {
+	port := startTestQueryServer(t, mcstatus.QueryServerOptions{
+		ChallengeLifetime: time.Second * 30,
+		RateLimit:         100,
+		RateBurst:         100,
+	})
+
+	basic, err := mcstatus.BasicQuery("127.0.0.1", port)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if basic.MOTD.Clean() != "A Minecraft Server" || basic.OnlinePlayers != 2 || basic.MaxPlayers != 20 || basic.HostPort != 25565 || basic.HostIP != "127.0.0.1" {
+		t.Fatalf("unexpected basic response: %+v", basic)
+	}
+
+	full, err := mcstatus.FullQuery("127.0.0.1", port)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	details, err := full.Details()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if details.Version != "1.20.4" || details.Software != "Paper on Bukkit 1.20.4" || len(details.Plugins) != 1 || len(details.Players) != 2 {
+		t.Fatalf("unexpected full response: %+v", details)
+	}
+}
+
+func TestQueryServerChallenge(t *testing.T) {
+	port := startTestQueryServer(t, mcstatus.QueryServerOptions{
+		ChallengeLifetime: time.Second * 30,
+		RateLimit:         100,
+		RateBurst:         100,
+	})
+
+	conn, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// A stat request with a token that was never issued must not be answered
+	if _, err = conn.Write([]byte{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond * 200))
+
+	if _, err = conn.Read(make([]byte, 1500)); err == nil {
+		t.Fatal("expected no response for an invalid challenge token")
+	}
+}
+
+func TestQueryServerRateLimit(t *testing.T) {
+	port := startTestQueryServer(t, mcstatus.QueryServerOptions{
+		ChallengeLifetime: time.Second * 30,
+		RateLimit:         0.001,
+		RateBurst:         2,
+	})
+
+	conn, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err = conn.Write([]byte{0xFE, 0xFD, 0x09, 0x00, 0x00, 0x00, 0x01}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	responses := 0
+	data := make([]byte, 1500)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(time.Millisecond * 200))
+
+		if _, err = conn.Read(data); err != nil {
+			break
+		}
+
+		responses++
+	}
+
+	if responses != 2 {
+		t.Fatalf("expected 2 responses within the burst, got %d", responses)
+	}
+}
+
+func TestQueryServerDefaults(t *testing.T) {
+	// Only the rate limit is set, every other option uses its default
+	port := startTestQueryServer(t, mcstatus.QueryServerOptions{
+		RateLimit: 100,
+	})
+
+	if _, err := mcstatus.FullQuery("127.0.0.1", port); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryServerMaxChallenges(t *testing.T) {
+	port := startTestQueryServer(t, mcstatus.QueryServerOptions{
+		ChallengeLifetime: time.Millisecond * 300,
+		MaxChallenges:     1,
+	})
+
+	if _, err := mcstatus.BasicQuery("127.0.0.1", port); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client on a new address is not issued a challenge until the first one expires
+	_, err := mcstatus.BasicQuery("127.0.0.1", port, mcstatus.QueryOptions{
+		Timeout:     time.Millisecond * 200,
+		MaxAttempts: 1,
+	})
+
+	if err == nil {
+		t.Fatal("expected no challenge to be issued over the max challenges")
+	}
+
+	// Expired challenges are pruned, which makes room for new ones
+	time.Sleep(time.Millisecond * 700)
+
+	if _, err = mcstatus.BasicQuery("127.0.0.1", port); err != nil {
+		t.Fatal(err)
+	}
+}
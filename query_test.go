@@ -1,9 +1,13 @@
 package mcstatus_test
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"log"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/PassTheMayo/mcstatus/v3"
 )
@@ -27,3 +31,137 @@ func TestFullQuery(t *testing.T) {
 
 	fmt.Println(response)
 }
+
+func serveTestQuery(conn net.PacketConn, drop int) {
+	data := make([]byte, 1500)
+
+	for {
+		n, addr, err := conn.ReadFrom(data)
+
+		if err != nil {
+			return
+		}
+
+		if n < 7 || data[0] != 0xFE || data[1] != 0xFD {
+			continue
+		}
+
+		if drop > 0 {
+			drop--
+
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		buf.WriteByte(data[2])
+		buf.Write(data[3:7])
+
+		if data[2] == 0x09 {
+			buf.WriteString("9513307\x00")
+		} else {
+			buf.WriteString("A Minecraft Server\x00SMP\x00world\x002\x0020\x00")
+			binary.Write(buf, binary.LittleEndian, uint16(25565))
+			buf.WriteString("127.0.0.1\x00")
+		}
+
+		conn.WriteTo(buf.Bytes(), addr)
+	}
+}
+
+func TestBasicQueryRetransmission(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestQuery(conn, 1)
+
+	response, err := mcstatus.BasicQuery("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.QueryOptions{
+		Timeout:       time.Second * 5,
+		SessionID:     1,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond * 50,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Attempts != 3 || response.OnlinePlayers != 2 || response.MaxPlayers != 20 || response.HostPort != 25565 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+}
+
+func TestBasicQueryLateHandshake(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// The response to the first handshake arrives late, just before the response to the retransmitted one, and
+	// the server only accepts the token it issued last
+	go (func() {
+		data := make([]byte, 1500)
+		handshakes := 0
+
+		for {
+			n, addr, err := conn.ReadFrom(data)
+
+			if err != nil {
+				return
+			}
+
+			if n < 7 || data[0] != 0xFE || data[1] != 0xFD {
+				continue
+			}
+
+			buf := &bytes.Buffer{}
+			buf.WriteByte(data[2])
+			buf.Write(data[3:7])
+
+			if data[2] == 0x09 {
+				handshakes++
+
+				if handshakes == 1 {
+					continue
+				}
+
+				conn.WriteTo(append(append([]byte{}, buf.Bytes()...), "1111\x00"...), addr)
+				conn.WriteTo(append(buf.Bytes(), "2222\x00"...), addr)
+
+				continue
+			}
+
+			if n < 11 || binary.BigEndian.Uint32(data[7:11]) != 2222 {
+				continue
+			}
+
+			buf.WriteString("A Minecraft Server\x00SMP\x00world\x002\x0020\x00")
+			binary.Write(buf, binary.LittleEndian, uint16(25565))
+			buf.WriteString("127.0.0.1\x00")
+
+			conn.WriteTo(buf.Bytes(), addr)
+		}
+	})()
+
+	response, err := mcstatus.BasicQuery("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.QueryOptions{
+		Timeout:       time.Second * 5,
+		SessionID:     1,
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond * 50,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Attempts != 4 || response.OnlinePlayers != 2 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+}
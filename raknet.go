@@ -0,0 +1,508 @@
+package mcstatus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// The size of the IP and UDP headers in front of a RakNet datagram, which count towards the MTU
+	rakNetUDPv4HeaderSize = 20 + 8
+	rakNetUDPv6HeaderSize = 40 + 8
+)
+
+var (
+	defaultBedrockConnectionOptions = BedrockConnectionOptions{
+		EnableSRV:       true,
+		Timeout:         time.Second * 5,
+		ClientGUID:      2,
+		ProtocolVersion: 11,
+		MTUSizes:        []uint16{1492, 1200, 576},
+		MaxAttempts:     2,
+		RetryInterval:   time.Millisecond * 300,
+	}
+)
+
+type BedrockConnectionOptions struct {
+	EnableSRV       bool
+	Timeout         time.Duration
+	ClientGUID      int64
+	ProtocolVersion byte
+	MTUSizes        []uint16
+	MaxAttempts     int
+	RetryInterval   time.Duration
+}
+
+type BedrockConnectionResponse struct {
+	ServerGUID      int64      `json:"server_guid"`
+	ProtocolVersion byte       `json:"protocol_version"`
+	Compatible      bool       `json:"compatible"`
+	MTU             uint16     `json:"mtu"`
+	Security        bool       `json:"security"`
+	Encryption      bool       `json:"encryption"`
+	ClientAddress   string     `json:"client_address"`
+	SRVResult       *SRVRecord `json:"srv_result"`
+}
+
+func (r BedrockConnectionResponse) String() string {
+	if !r.Compatible {
+		return fmt.Sprintf("Server GUID: %d\nProtocol Version: %d (incompatible)", r.ServerGUID, r.ProtocolVersion)
+	}
+
+	return fmt.Sprintf(
+		"Server GUID: %d\nProtocol Version: %d\nMTU: %d\nSecurity: %t\nEncryption: %t\nClient Address: %s",
+		r.ServerGUID,
+		r.ProtocolVersion,
+		r.MTU,
+		r.Security,
+		r.Encryption,
+		r.ClientAddress,
+	)
+}
+
+// ProbeBedrockConnection performs the first half of a RakNet connection handshake with a Bedrock server,
+// discovering the largest MTU that reaches the server, starting from the first of the MTU sizes and working
+// downwards. Each MTU size is given at most an equal share of the timeout. If the server does not support the
+// RakNet protocol version of the client, the server's protocol version is returned and Compatible is false.
+func ProbeBedrockConnection(host string, port uint16, options ...BedrockConnectionOptions) (*BedrockConnectionResponse, error) {
+	opts := parseBedrockConnectionOptions(options...)
+
+	var srvResult *SRVRecord = nil
+
+	if opts.EnableSRV {
+		record, err := lookupSRV(host, port)
+
+		if err == nil && record != nil {
+			host = record.Target
+			port = record.Port
+
+			srvResult = &SRVRecord{
+				Host: record.Target,
+				Port: record.Port,
+			}
+		}
+	}
+
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(host, strconv.Itoa(int(port))), opts.Timeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	deadline := time.Now().Add(opts.Timeout)
+
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	headerSize := rakNetUDPv4HeaderSize
+
+	if conn.RemoteAddr().(*net.UDPAddr).IP.To4() == nil {
+		headerSize = rakNetUDPv6HeaderSize
+	}
+
+	// Probes that are too large for a link along the path must be dropped rather than fragmented, otherwise every
+	// MTU size would reach the server
+	if err = setDontFragment(conn.(*net.UDPConn), headerSize == rakNetUDPv6HeaderSize); err != nil {
+		return nil, err
+	}
+
+	response := &BedrockConnectionResponse{
+		ProtocolVersion: opts.ProtocolVersion,
+		SRVResult:       srvResult,
+	}
+
+	var reply []byte
+
+	// Open connection request 1 packet
+	// https://wiki.vg/Raknet_Protocol#Open_Connection_Request_1
+	{
+		sizeTimeout := opts.Timeout / time.Duration(len(opts.MTUSizes))
+
+		// A size is given up on once every attempt has had its backoff to be answered in
+		var window time.Duration
+
+		for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+			window += retryBackoff(opts.RetryInterval, attempt)
+		}
+
+		if window > 0 && window < sizeTimeout {
+			sizeTimeout = window
+		}
+
+		for i, mtu := range opts.MTUSizes {
+			sizeDeadline := time.Now().Add(sizeTimeout)
+
+			if i == len(opts.MTUSizes)-1 || sizeDeadline.After(deadline) {
+				sizeDeadline = deadline
+			}
+
+			request, err := encodeOpenConnectionRequest1(opts.ProtocolVersion, mtu, headerSize)
+
+			if err != nil {
+				return nil, err
+			}
+
+			data, _, err := exchangeUDP(conn, sizeDeadline, opts.MaxAttempts, opts.RetryInterval, func(int) []byte { return request }, func(data []byte) bool {
+				return (len(data) >= 17 && data[0] == 0x06 && bytes.Equal(data[1:17], bedrockMagic)) ||
+					(len(data) >= 18 && data[0] == 0x19 && bytes.Equal(data[2:18], bedrockMagic))
+			})
+
+			if err != nil {
+				var netErr net.Error
+
+				// Datagrams larger than the known path MTU are rejected by the operating system, and
+				// datagrams that are dropped on the way time out, both mean a smaller size should be tried
+				if errors.Is(err, syscall.EMSGSIZE) || (errors.As(err, &netErr) && netErr.Timeout() && i < len(opts.MTUSizes)-1) {
+					continue
+				}
+
+				return nil, err
+			}
+
+			reply = data
+
+			break
+		}
+
+		if reply == nil {
+			return nil, ErrUnexpectedResponse
+		}
+	}
+
+	// Incompatible protocol version packet
+	// https://wiki.vg/Raknet_Protocol#Incompatible_Protocol_Version
+	if reply[0] == 0x19 {
+		r := bytes.NewReader(reply[1:])
+
+		// Protocol version - byte
+		protocolVersion, err := r.ReadByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Magic - bytes
+		if _, err := io.CopyN(io.Discard, r, 16); err != nil {
+			return nil, err
+		}
+
+		// Server GUID - int64
+		if err := binary.Read(r, binary.BigEndian, &response.ServerGUID); err != nil {
+			return nil, err
+		}
+
+		response.ProtocolVersion = protocolVersion
+		response.Compatible = false
+
+		return response, nil
+	}
+
+	var cookie []byte
+
+	// Open connection reply 1 packet
+	// https://wiki.vg/Raknet_Protocol#Open_Connection_Reply_1
+	{
+		r := bytes.NewReader(reply[17:])
+
+		// Server GUID - int64
+		if err := binary.Read(r, binary.BigEndian, &response.ServerGUID); err != nil {
+			return nil, err
+		}
+
+		// Use security - bool
+		security, err := r.ReadByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		response.Security = security != 0x00
+
+		// Cookie - int32
+		if response.Security {
+			cookie = make([]byte, 4)
+
+			if _, err := io.ReadFull(r, cookie); err != nil {
+				return nil, err
+			}
+		}
+
+		// MTU - uint16, any public key sent along with the cookie comes before it
+		if r.Len() < 2 {
+			return nil, ErrUnexpectedResponse
+		}
+
+		response.MTU = binary.BigEndian.Uint16(reply[len(reply)-2:])
+		response.Compatible = true
+	}
+
+	// Open connection request 2 packet
+	// https://wiki.vg/Raknet_Protocol#Open_Connection_Request_2
+	buf := &bytes.Buffer{}
+
+	{
+		// Packet ID - byte
+		if err := buf.WriteByte(0x07); err != nil {
+			return nil, err
+		}
+
+		// Magic - bytes
+		if _, err := buf.Write(bedrockMagic); err != nil {
+			return nil, err
+		}
+
+		if cookie != nil {
+			// Cookie - int32
+			if _, err := buf.Write(cookie); err != nil {
+				return nil, err
+			}
+
+			// Client supports security - bool
+			if err := buf.WriteByte(0x00); err != nil {
+				return nil, err
+			}
+		}
+
+		// Server address - address
+		if err := writeRakNetAddress(buf, conn.RemoteAddr().(*net.UDPAddr)); err != nil {
+			return nil, err
+		}
+
+		// MTU - uint16
+		if err := binary.Write(buf, binary.BigEndian, response.MTU); err != nil {
+			return nil, err
+		}
+
+		// Client GUID - int64
+		if err := binary.Write(buf, binary.BigEndian, opts.ClientGUID); err != nil {
+			return nil, err
+		}
+	}
+
+	data, _, err := exchangeUDP(conn, deadline, opts.MaxAttempts, opts.RetryInterval, func(int) []byte { return buf.Bytes() }, func(data []byte) bool {
+		return len(data) >= 17 && data[0] == 0x08 && bytes.Equal(data[1:17], bedrockMagic)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Open connection reply 2 packet
+	// https://wiki.vg/Raknet_Protocol#Open_Connection_Reply_2
+	{
+		r := bytes.NewReader(data[17:])
+
+		// Server GUID - int64
+		if err := binary.Read(r, binary.BigEndian, &response.ServerGUID); err != nil {
+			return nil, err
+		}
+
+		// Client address - address
+		clientAddress, err := readRakNetAddress(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		response.ClientAddress = clientAddress.String()
+
+		// MTU - uint16
+		if err := binary.Read(r, binary.BigEndian, &response.MTU); err != nil {
+			return nil, err
+		}
+
+		// Encryption enabled - bool
+		encryption, err := r.ReadByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		response.Encryption = encryption != 0x00
+	}
+
+	return response, nil
+}
+
+// encodeOpenConnectionRequest1 creates an open connection request 1 packet padded so that the whole
+// datagram, including the IP and UDP headers of the header size, is the size of the MTU
+func encodeOpenConnectionRequest1(protocolVersion byte, mtu uint16, headerSize int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// Packet ID - byte
+	if err := buf.WriteByte(0x05); err != nil {
+		return nil, err
+	}
+
+	// Magic - bytes
+	if _, err := buf.Write(bedrockMagic); err != nil {
+		return nil, err
+	}
+
+	// Protocol version - byte
+	if err := buf.WriteByte(protocolVersion); err != nil {
+		return nil, err
+	}
+
+	// MTU padding - bytes
+	if padding := int(mtu) - headerSize - buf.Len(); padding > 0 {
+		if _, err := buf.Write(make([]byte, padding)); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeRakNetAddress writes a UDP address in the RakNet system address format
+// https://wiki.vg/Raknet_Protocol#Data_types
+func writeRakNetAddress(w *bytes.Buffer, addr *net.UDPAddr) error {
+	if ip := addr.IP.To4(); ip != nil {
+		// Version - byte
+		if err := w.WriteByte(4); err != nil {
+			return err
+		}
+
+		// Address - bytes, every byte is inverted
+		for _, b := range ip {
+			if err := w.WriteByte(^b); err != nil {
+				return err
+			}
+		}
+
+		// Port - uint16
+		return binary.Write(w, binary.BigEndian, uint16(addr.Port))
+	}
+
+	// Version - byte
+	if err := w.WriteByte(6); err != nil {
+		return err
+	}
+
+	// Address family - uint16
+	if err := binary.Write(w, binary.LittleEndian, uint16(23)); err != nil {
+		return err
+	}
+
+	// Port - uint16
+	if err := binary.Write(w, binary.BigEndian, uint16(addr.Port)); err != nil {
+		return err
+	}
+
+	// Flow info - uint32
+	if err := binary.Write(w, binary.BigEndian, uint32(0)); err != nil {
+		return err
+	}
+
+	// Address - bytes
+	if _, err := w.Write(addr.IP.To16()); err != nil {
+		return err
+	}
+
+	// Scope ID - uint32
+	return binary.Write(w, binary.BigEndian, uint32(0))
+}
+
+// readRakNetAddress reads a UDP address in the RakNet system address format
+// https://wiki.vg/Raknet_Protocol#Data_types
+func readRakNetAddress(r *bytes.Reader) (*net.UDPAddr, error) {
+	version, err := r.ReadByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case 4:
+		{
+			ip := make(net.IP, 4)
+
+			if _, err := io.ReadFull(r, ip); err != nil {
+				return nil, err
+			}
+
+			for i := range ip {
+				ip[i] = ^ip[i]
+			}
+
+			var port uint16
+
+			if err := binary.Read(r, binary.BigEndian, &port); err != nil {
+				return nil, err
+			}
+
+			return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+		}
+	case 6:
+		{
+			// Address family - uint16
+			if _, err := io.CopyN(io.Discard, r, 2); err != nil {
+				return nil, err
+			}
+
+			var port uint16
+
+			if err := binary.Read(r, binary.BigEndian, &port); err != nil {
+				return nil, err
+			}
+
+			// Flow info - uint32
+			if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+				return nil, err
+			}
+
+			ip := make(net.IP, 16)
+
+			if _, err := io.ReadFull(r, ip); err != nil {
+				return nil, err
+			}
+
+			// Scope ID - uint32
+			if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+				return nil, err
+			}
+
+			return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+		}
+	default:
+		{
+			return nil, ErrUnexpectedResponse
+		}
+	}
+}
+
+func parseBedrockConnectionOptions(opts ...BedrockConnectionOptions) BedrockConnectionOptions {
+	if len(opts) < 1 {
+		return defaultBedrockConnectionOptions
+	}
+
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultBedrockConnectionOptions.Timeout
+	}
+
+	if len(options.MTUSizes) < 1 {
+		options.MTUSizes = defaultBedrockConnectionOptions.MTUSizes
+	}
+
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = defaultBedrockConnectionOptions.MaxAttempts
+	}
+
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = defaultBedrockConnectionOptions.RetryInterval
+	}
+
+	return options
+}
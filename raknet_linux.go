@@ -0,0 +1,32 @@
+package mcstatus
+
+import (
+	"net"
+	"syscall"
+)
+
+// setDontFragment sets the don't fragment bit on every datagram sent by the connection, datagrams larger than
+// the path MTU are then rejected with EMSGSIZE or dropped instead of being fragmented
+func setDontFragment(conn *net.UDPConn, ipv6 bool) error {
+	raw, err := conn.SyscallConn()
+
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+
+	err = raw.Control(func(fd uintptr) {
+		if ipv6 {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_DO)
+		} else {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package mcstatus
+
+import "net"
+
+// setDontFragment does nothing on platforms where the don't fragment bit cannot be set, oversized datagrams
+// are fragmented by the operating system and MTU discovery only detects the MTU of the local link
+func setDontFragment(conn *net.UDPConn, ipv6 bool) error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package mcstatus_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func serveTestRakNet(conn net.PacketConn, protocolVersion byte, maxMTU int) {
+	data := make([]byte, 2048)
+
+	for {
+		n, addr, err := conn.ReadFrom(data)
+
+		if err != nil {
+			return
+		}
+
+		if n < 17 {
+			continue
+		}
+
+		magic := data[1:17]
+		buf := &bytes.Buffer{}
+
+		switch data[0] {
+		case 0x05:
+			{
+				if n+28 > maxMTU {
+					continue
+				}
+
+				if data[17] != protocolVersion {
+					buf.WriteByte(0x19)
+					buf.WriteByte(protocolVersion)
+					buf.Write(magic)
+					binary.Write(buf, binary.BigEndian, int64(1234))
+
+					break
+				}
+
+				buf.WriteByte(0x06)
+				buf.Write(magic)
+				binary.Write(buf, binary.BigEndian, int64(1234))
+				buf.WriteByte(0x00)
+				binary.Write(buf, binary.BigEndian, uint16(n+28))
+			}
+		case 0x07:
+			{
+				clientAddr := addr.(*net.UDPAddr)
+
+				buf.WriteByte(0x08)
+				buf.Write(magic)
+				binary.Write(buf, binary.BigEndian, int64(1234))
+				buf.WriteByte(4)
+
+				for _, b := range clientAddr.IP.To4() {
+					buf.WriteByte(^b)
+				}
+
+				binary.Write(buf, binary.BigEndian, uint16(clientAddr.Port))
+				buf.Write(data[n-10 : n-8])
+				buf.WriteByte(0x00)
+			}
+		default:
+			{
+				continue
+			}
+		}
+
+		conn.WriteTo(buf.Bytes(), addr)
+	}
+}
+
+func TestProbeBedrockConnection(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestRakNet(conn, 11, 1200)
+
+	options := mcstatus.BedrockConnectionOptions{
+		EnableSRV:       false,
+		Timeout:         time.Second * 3,
+		ClientGUID:      2,
+		ProtocolVersion: 11,
+		MTUSizes:        []uint16{1492, 1200, 576},
+		MaxAttempts:     2,
+		RetryInterval:   time.Millisecond * 100,
+	}
+
+	response, err := mcstatus.ProbeBedrockConnection("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Compatible || response.MTU != 1200 || response.ServerGUID != 1234 || response.Security {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	options.ProtocolVersion = 10
+
+	response, err = mcstatus.ProbeBedrockConnection("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Compatible || response.ProtocolVersion != 11 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+}
+
+func TestProbeBedrockConnectionDefaultMTUSizes(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestRakNet(conn, 11, 1200)
+
+	// The MTU sizes are not set, so the default sizes are probed
+	response, err := mcstatus.ProbeBedrockConnection("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockConnectionOptions{
+		Timeout:         time.Second * 3,
+		ClientGUID:      2,
+		ProtocolVersion: 11,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Compatible || response.MTU != 1200 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+}
+
+func TestProbeBedrockConnectionIPv6(t *testing.T) {
+	conn, err := net.ListenPacket("udp6", "[::1]:0")
+
+	if err != nil {
+		t.Skip("IPv6 is not available:", err)
+	}
+
+	defer conn.Close()
+
+	sizes := make(chan int, 1)
+
+	go (func() {
+		data := make([]byte, 2048)
+
+		n, _, err := conn.ReadFrom(data)
+
+		if err == nil && data[0] == 0x05 {
+			sizes <- n
+		}
+	})()
+
+	mcstatus.ProbeBedrockConnection("::1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockConnectionOptions{
+		EnableSRV:       false,
+		Timeout:         time.Millisecond * 100,
+		ProtocolVersion: 11,
+		MTUSizes:        []uint16{1200},
+		MaxAttempts:     1,
+	})
+
+	// The IPv6 header is 20 bytes larger than the IPv4 header, which leaves less room for the padding
+	select {
+	case n := <-sizes:
+		if n != 1200-48 {
+			t.Fatalf("expected a datagram of %d bytes, got %d", 1200-48, n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no datagram was received")
+	}
+}
@@ -0,0 +1,39 @@
+package mcstatus
+
+import (
+	"net"
+	"syscall"
+)
+
+const (
+	// https://learn.microsoft.com/en-us/windows/win32/winsock/ipproto-ip-socket-options
+	ipDontFragment = 14
+	// https://learn.microsoft.com/en-us/windows/win32/winsock/ipproto-ipv6-socket-options
+	ipv6DontFragment = 14
+)
+
+// setDontFragment sets the don't fragment bit on every datagram sent by the connection, datagrams larger than
+// the path MTU are then rejected or dropped instead of being fragmented
+func setDontFragment(conn *net.UDPConn, ipv6 bool) error {
+	raw, err := conn.SyscallConn()
+
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+
+	err = raw.Control(func(fd uintptr) {
+		if ipv6 {
+			sockErr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IPV6, ipv6DontFragment, 1)
+		} else {
+			sockErr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IP, ipDontFragment, 1)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
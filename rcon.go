@@ -4,9 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -16,17 +17,92 @@ var (
 	}
 )
 
+const (
+	rconTypeResponse int32 = 0
+	rconTypeCommand  int32 = 2
+	rconTypeLogin    int32 = 3
+)
+
+// RCONMode is the flavour of the RCON protocol spoken by the server
+type RCONMode int
+
+const (
+	// RCONModeMinecraft is the protocol as implemented by vanilla Minecraft servers
+	RCONModeMinecraft RCONMode = iota
+	// RCONModeSource is the protocol as implemented by Source engine servers, which send an extra empty
+	// response before the login response, and answer the empty sentinel packet with two packets
+	RCONModeSource
+)
+
+// RCONTerminator is the way the client detects the end of the output of a command
+type RCONTerminator int
+
+const (
+	// RCONTerminatorSentinel sends an empty packet after every command, and the output is complete once the
+	// response to it arrives. This supports output that is split into multiple packets.
+	RCONTerminatorSentinel RCONTerminator = iota
+	// RCONTerminatorSinglePacket treats the first response packet as the complete output, for servers that
+	// do not respond to packets of an unknown type
+	RCONTerminatorSinglePacket
+)
+
+// RCON is a client for the RCON protocol. Execute is safe for concurrent use and returns the output of the
+// command, while the output of commands sent with Run is delivered on the Messages channel. The Messages
+// channel is buffered, but must be read from if Run is used, otherwise the connection will stall.
 type RCON struct {
-	conn        *net.Conn
+	conn        net.Conn
 	r           *bufio.Reader
+	address     string
 	Messages    chan string
-	runTrigger  chan bool
+	options     RCONOptions
 	authSuccess bool
 	requestID   int32
+	pending     map[int32]*rconExecution
+	err         error
+	done        chan struct{}
+	lock        sync.Mutex
+	writeLock   sync.Mutex
+	wg          sync.WaitGroup
 }
 
+// RCONOptions are the options of an RCON client. The max packet size is the largest packet in bytes, including
+// the length field, that is sent to or accepted from the server, which defaults to 4096 for Source servers
+// and 65536 for Minecraft servers, whose responses can exceed 4096 bytes when they contain multi-byte characters.
+// If the auditor is set, every command run by the client is recorded with it, and a command that could not be
+// recorded returns an RCONAuditError along with its output. If the policy is set, commands are checked against
+// it before they are sent.
 type RCONOptions struct {
-	Timeout time.Duration
+	Timeout       time.Duration
+	Mode          RCONMode
+	MaxPacketSize int
+	Terminator    RCONTerminator
+	Auditor       RCONAuditor
+	Policy        *RCONPolicy
+}
+
+// rconPacket is a single packet of the RCON protocol
+// https://wiki.vg/RCON#Packet_Format
+type rconPacket struct {
+	RequestID int32
+	Type      int32
+	Payload   string
+}
+
+// rconExecution collects the output of a command sent by Execute. The output of a command may be split into
+// multiple packets with the same request ID, so a second, empty packet is sent after the command. The server
+// handles packets in order, so the response to the second packet marks the end of the command output.
+type rconExecution struct {
+	commandID  int32
+	sentinelID int32
+	output     bytes.Buffer
+	abandoned  bool
+	finished   bool
+	result     chan rconResult
+}
+
+type rconResult struct {
+	output string
+	err    error
 }
 
 // NewRCON creates a new RCON client from the options parameter
@@ -34,8 +110,7 @@ func NewRCON() *RCON {
 	return &RCON{
 		conn:        nil,
 		r:           nil,
-		Messages:    make(chan string),
-		runTrigger:  make(chan bool),
+		Messages:    make(chan string, 64),
 		authSuccess: false,
 		requestID:   0,
 	}
@@ -44,7 +119,9 @@ func NewRCON() *RCON {
 func (r *RCON) Dial(host string, port uint16, options ...RCONOptions) error {
 	opts := parseRCONOptions(options...)
 
-	conn, err := net.DialTimeout("tcp4", fmt.Sprintf("%s:%d", host, port), opts.Timeout)
+	address := net.JoinHostPort(host, strconv.Itoa(int(port)))
+
+	conn, err := net.DialTimeout("tcp4", address, opts.Timeout)
 
 	if err != nil {
 		return err
@@ -54,267 +131,469 @@ func (r *RCON) Dial(host string, port uint16, options ...RCONOptions) error {
 		return err
 	}
 
-	r.conn = &conn
+	r.lock.Lock()
+
+	r.conn = conn
 	r.r = bufio.NewReader(conn)
+	r.address = address
+	r.options = opts
+	r.err = nil
+
+	r.lock.Unlock()
 
 	return nil
 }
 
 func (r *RCON) Login(password string) error {
-	if r.conn == nil {
+	r.lock.Lock()
+	conn, authSuccess := r.conn, r.authSuccess
+	r.lock.Unlock()
+
+	if conn == nil {
 		return ErrNotConnected
 	}
 
-	if r.authSuccess {
+	if authSuccess {
 		return ErrAlreadyLoggedIn
 	}
 
 	// Login request packet
 	// https://wiki.vg/RCON#3:_Login
-	{
-		buf := &bytes.Buffer{}
+	if err := writeRCONPacket(conn, rconPacket{RequestID: 0, Type: rconTypeLogin, Payload: password}, r.options.maxPacketSize()); err != nil {
+		return err
+	}
 
-		// Length - int32
-		if err := binary.Write(buf, binary.LittleEndian, int32(10+len(password))); err != nil {
-			return err
-		}
+	// Login response packet
+	// https://wiki.vg/RCON#3:_Login
+	{
+		packet, err := readRCONPacket(r.r, r.options.maxPacketSize())
 
-		// Request ID - int32
-		if err := binary.Write(buf, binary.LittleEndian, int32(0)); err != nil {
+		if err != nil {
 			return err
 		}
 
-		// Type - int32
-		if err := binary.Write(buf, binary.LittleEndian, int32(3)); err != nil {
-			return err
+		// Source servers send an empty response before the login response
+		// https://developer.valvesoftware.com/wiki/Source_RCON_Protocol#SERVERDATA_AUTH_RESPONSE
+		if r.options.Mode == RCONModeSource && packet.Type == rconTypeResponse {
+			if packet, err = readRCONPacket(r.r, r.options.maxPacketSize()); err != nil {
+				return err
+			}
 		}
 
-		// Payload - null-terminated string
-		if _, err := buf.Write(append([]byte(password), 0x00)); err != nil {
-			return err
+		if packet.RequestID == -1 {
+			return ErrInvalidPassword
+		} else if packet.RequestID != 0 {
+			return ErrUnexpectedResponse
 		}
 
-		// Padding - byte
-		if err := buf.WriteByte(0x00); err != nil {
-			return err
+		if packet.Type != rconTypeCommand {
+			return ErrUnexpectedResponse
 		}
+	}
 
-		if _, err := io.Copy(*r.conn, buf); err != nil {
-			return err
-		}
+	// The deadline set when dialing only covers the login, commands set their own deadlines
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return err
 	}
 
-	// Login response packet
-	// https://wiki.vg/RCON#3:_Login
-	{
-		var packetLength int32
+	r.lock.Lock()
 
-		// Length - int32
-		{
-			if err := binary.Read(r.r, binary.LittleEndian, &packetLength); err != nil {
-				return err
-			}
-		}
+	r.authSuccess = true
+	r.pending = make(map[int32]*rconExecution)
+	r.done = make(chan struct{})
 
-		// Request ID - int32
-		{
-			var requestID int32
+	r.lock.Unlock()
 
-			if err := binary.Read(r.r, binary.LittleEndian, &requestID); err != nil {
-				return err
-			}
+	r.wg.Add(1)
 
-			if requestID == -1 {
-				return ErrInvalidPassword
-			} else if requestID != 0 {
-				return ErrUnexpectedResponse
-			}
-		}
+	go r.receive(conn, r.done)
 
-		// Type - int32
-		{
-			var packetType int32
+	return nil
+}
 
-			if err := binary.Read(r.r, binary.LittleEndian, &packetType); err != nil {
-				return err
-			}
+// Run sends the command to the server without waiting for the output, which is sent on the Messages channel
+func (r *RCON) Run(command string) error {
+	return r.RunAs(RCONCaller{}, command)
+}
 
-			if packetType != 2 {
-				return ErrUnexpectedResponse
-			}
-		}
+func (r *RCON) run(command string) error {
+	conn, err := r.loggedIn()
 
-		// Remaining bytes
-		{
-			data := make([]byte, packetLength-8)
+	if err != nil {
+		return err
+	}
 
-			if _, err := (*r.r).Read(data); err != nil {
-				return err
-			}
-		}
+	r.lock.Lock()
+
+	r.requestID++
+	requestID := r.requestID
+
+	r.lock.Unlock()
+
+	// Command packet
+	// https://wiki.vg/RCON#2:_Command
+	return r.write(conn, rconPacket{RequestID: requestID, Type: rconTypeCommand, Payload: command})
+}
+
+// Execute sends the command to the server and waits for the complete output of the command, it is safe to
+// call from multiple goroutines
+func (r *RCON) Execute(command string) (string, error) {
+	return r.executeAudited(RCONCaller{}, command, r.options.Timeout)
+}
+
+// ExecuteTimeout is the same as Execute, but waits for the output until the timeout instead of the timeout
+// the client was dialed with
+func (r *RCON) ExecuteTimeout(command string, timeout time.Duration) (string, error) {
+	return r.executeAudited(RCONCaller{}, command, timeout)
+}
+
+// ExecuteFormatted is the same as Execute, but parses the formatting codes in the output so that it can be
+// rendered with Clean, HTML or ANSI. Messages received on the Messages channel can be parsed with ParseMOTD.
+func (r *RCON) ExecuteFormatted(command string) (*MOTD, error) {
+	output, err := r.Execute(command)
+
+	if err != nil {
+		return nil, err
 	}
 
-	r.authSuccess = true
+	return ParseMOTD(output)
+}
 
-	if err := (*r.conn).SetReadDeadline(time.Time{}); err != nil {
-		return err
+// ping sends only the empty sentinel packet and waits for the response, to check that the server is still
+// handling packets without running a command
+func (r *RCON) ping(timeout time.Duration) error {
+	// Without a sentinel there is nothing that the server is guaranteed to respond to
+	if r.options.Terminator == RCONTerminatorSinglePacket {
+		if !r.healthy() {
+			return ErrConnectionClosed
+		}
+
+		return nil
 	}
 
-	go (func() {
-		for {
-			// TODO figure out EOF issue, and how to not continuously loop with EOF errors when client is open
+	_, err := r.execute("", true, timeout)
 
-			err := r.readMessage()
+	return err
+}
 
-			if err != nil {
-				fmt.Println(err)
-			}
-		}
-	})()
+// healthy returns whether the client is logged in and the connection has not failed
+func (r *RCON) healthy() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
-	return nil
+	return r.err == nil && r.conn != nil && r.authSuccess
 }
 
-func (r *RCON) Run(command string) error {
-	if r.conn == nil {
-		return ErrNotConnected
+func (r *RCON) execute(command string, ping bool, timeout time.Duration) (string, error) {
+	conn, err := r.loggedIn()
+
+	if err != nil {
+		return "", err
 	}
 
-	if !r.authSuccess {
-		return ErrNotLoggedIn
+	r.lock.Lock()
+
+	if r.err != nil || r.pending == nil {
+		r.lock.Unlock()
+
+		return "", ErrConnectionClosed
 	}
 
-	r.requestID++
+	execution := &rconExecution{
+		commandID:  r.requestID + 1,
+		sentinelID: r.requestID + 2,
+		result:     make(chan rconResult, 1),
+	}
 
-	// Command packet
-	// https://wiki.vg/RCON#2:_Command
-	{
-		buf := &bytes.Buffer{}
+	r.requestID += 2
+	r.pending[execution.commandID] = execution
+	r.pending[execution.sentinelID] = execution
 
-		// Length - int32
-		if err := binary.Write(buf, binary.LittleEndian, int32(10+len(command))); err != nil {
-			return err
-		}
+	r.lock.Unlock()
 
-		// Request ID - int32
-		if err := binary.Write(buf, binary.LittleEndian, r.requestID); err != nil {
-			return err
-		}
+	// Both packets are written while holding the write lock, so that no other command can be sent in between
+	// them and have its output mistaken for the output of this command
+	r.writeLock.Lock()
 
-		// Type - int32
-		if err := binary.Write(buf, binary.LittleEndian, int32(2)); err != nil {
-			return err
-		}
+	if !ping {
+		err = r.writeLocked(conn, rconPacket{RequestID: execution.commandID, Type: rconTypeCommand, Payload: command})
+	}
 
-		// Payload - null-terminated string
-		if _, err := buf.Write(append([]byte(command), 0x00)); err != nil {
-			return err
-		}
+	if err == nil && r.options.Terminator == RCONTerminatorSentinel {
+		err = r.writeLocked(conn, rconPacket{RequestID: execution.sentinelID, Type: rconTypeResponse, Payload: ""})
+	}
 
-		if err := buf.WriteByte(0x00); err != nil {
-			return err
-		}
+	r.writeLock.Unlock()
 
-		if _, err := io.Copy(*r.conn, buf); err != nil {
-			return err
-		}
+	if err != nil {
+		r.lock.Lock()
+		delete(r.pending, execution.commandID)
+		delete(r.pending, execution.sentinelID)
+		r.lock.Unlock()
+
+		return "", err
 	}
 
-	return nil
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-execution.result:
+		return result.output, result.err
+	case <-timer.C:
+		{
+			// The output may still arrive later, so the request IDs are kept until the sentinel response so that
+			// the output is not sent to the Messages channel
+			r.lock.Lock()
+			execution.abandoned = true
+			r.lock.Unlock()
+
+			return "", ErrTimeout
+		}
+	}
 }
 
 func (r *RCON) Close() error {
+	r.lock.Lock()
+
+	conn, done := r.conn, r.done
+
 	r.authSuccess = false
 	r.requestID = 0
+	r.conn = nil
+	r.done = nil
+	r.err = nil
 
-	if r.conn != nil {
-		if err := (*r.conn).Close(); err != nil {
-			return err
-		}
+	r.lock.Unlock()
+
+	if done != nil {
+		close(done)
 	}
 
-	r.conn = nil
+	var err error
 
-	return nil
+	if conn != nil {
+		err = conn.Close()
+	}
+
+	r.wg.Wait()
+
+	return err
 }
 
-func (r *RCON) readMessage() error {
-	// Command response packet
-	// https://wiki.vg/RCON#0:_Command_response
-	{
-		var packetLength int32
+// loggedIn returns the connection if the client is logged in, or the error that caused the connection to fail
+func (r *RCON) loggedIn() (net.Conn, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
-		// Length - int32
-		{
-			// TODO convert to binary.Read() for the rest of the package
-			data := make([]byte, 4)
+	if r.err != nil {
+		return nil, r.err
+	}
 
-			n, err := (*r.r).Read(data)
+	if r.conn == nil {
+		return nil, ErrNotConnected
+	}
 
-			if err != nil {
-				return err
-			}
+	if !r.authSuccess {
+		return nil, ErrNotLoggedIn
+	}
 
-			if n < 4 {
-				return nil
-			}
+	return r.conn, nil
+}
 
-			packetLength = int32(binary.LittleEndian.Uint16(data))
-		}
+func (r *RCON) write(conn net.Conn, packet rconPacket) error {
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
 
-		// Request ID - int32
-		{
-			data := make([]byte, 4)
+	return r.writeLocked(conn, packet)
+}
 
-			n, err := (*r.r).Read(data)
+func (r *RCON) writeLocked(conn net.Conn, packet rconPacket) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(r.options.Timeout)); err != nil {
+		return err
+	}
 
-			if err != nil {
-				return err
-			}
+	return writeRCONPacket(conn, packet, r.options.maxPacketSize())
+}
+
+// receive reads packets from the connection and passes them to the command they belong to, until the
+// connection fails or the client is closed
+func (r *RCON) receive(conn net.Conn, done chan struct{}) {
+	defer r.wg.Done()
 
-			if n < 4 {
-				return nil
+	reader := r.r
+
+	for {
+		packet, err := readRCONPacket(reader, r.options.maxPacketSize())
+
+		if err != nil {
+			select {
+			case <-done:
+				err = ErrConnectionClosed
+			default:
 			}
+
+			r.fail(err)
+
+			return
 		}
 
-		// Type - int32
-		{
-			data := make([]byte, 4)
+		r.lock.Lock()
 
-			n, err := (*r.r).Read(data)
+		execution, ok := r.pending[packet.RequestID]
 
-			if err != nil {
-				return err
+		if ok {
+			if packet.RequestID == execution.commandID {
+				execution.output.WriteString(packet.Payload)
 			}
 
-			if n < 4 {
-				return nil
-			}
+			if packet.RequestID == execution.sentinelID && r.options.Mode == RCONModeSource && !execution.finished {
+				// Source servers answer the sentinel with a second packet, which is dropped once it arrives
+				delete(r.pending, execution.commandID)
+
+				r.finish(execution)
+			} else if packet.RequestID == execution.sentinelID || r.options.Terminator == RCONTerminatorSinglePacket {
+				delete(r.pending, execution.commandID)
+				delete(r.pending, execution.sentinelID)
 
-			if binary.LittleEndian.Uint32(data) != 0 {
-				return ErrUnexpectedResponse
+				r.finish(execution)
 			}
 		}
 
-		// Payload - null-terminated string
-		{
-			data := make([]byte, packetLength-8)
+		r.lock.Unlock()
 
-			n, err := (*r.r).Read(data)
+		if ok {
+			continue
+		}
 
-			if err != nil {
-				return err
-			}
+		select {
+		case r.Messages <- packet.Payload:
+		case <-done:
+			{
+				r.fail(ErrConnectionClosed)
 
-			if n < 1 {
-				return nil
+				return
 			}
+		}
+	}
+}
+
+// finish returns the output to the command if it is still waiting for it, the client must be locked
+func (r *RCON) finish(execution *rconExecution) {
+	if execution.finished {
+		return
+	}
+
+	execution.finished = true
+
+	if !execution.abandoned {
+		execution.result <- rconResult{output: execution.output.String()}
+	}
+}
 
-			r.Messages <- string(data)
+// fail stores the error that stopped the connection and returns it to every command that is waiting for output
+func (r *RCON) fail(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.err == nil && r.conn != nil {
+		r.err = err
+	}
+
+	for id, execution := range r.pending {
+		delete(r.pending, id)
+
+		if !execution.finished && !execution.abandoned {
+			execution.finished = true
+			execution.result <- rconResult{err: err}
 		}
 	}
+}
 
-	return nil
+// readRCONPacket reads a single packet from the reader
+// https://wiki.vg/RCON#Packet_Format
+func readRCONPacket(r io.Reader, maxPacketSize int) (*rconPacket, error) {
+	var packetLength int32
+
+	// Length - int32
+	if err := binary.Read(r, binary.LittleEndian, &packetLength); err != nil {
+		return nil, err
+	}
+
+	if packetLength < 10 {
+		return nil, ErrUnexpectedResponse
+	}
+
+	if int(packetLength)+4 > maxPacketSize {
+		return nil, ErrPacketTooLarge
+	}
+
+	data := make([]byte, packetLength)
+
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return &rconPacket{
+		// Request ID - int32
+		RequestID: int32(binary.LittleEndian.Uint32(data[0:4])),
+		// Type - int32
+		Type: int32(binary.LittleEndian.Uint32(data[4:8])),
+		// Payload - null-terminated string, followed by a padding byte
+		Payload: string(bytes.TrimRight(data[8:], "\x00")),
+	}, nil
+}
+
+// writeRCONPacket writes a single packet to the writer
+// https://wiki.vg/RCON#Packet_Format
+func writeRCONPacket(w io.Writer, packet rconPacket, maxPacketSize int) error {
+	if 14+len(packet.Payload) > maxPacketSize {
+		return ErrPacketTooLarge
+	}
+
+	buf := &bytes.Buffer{}
+
+	// Length - int32
+	if err := binary.Write(buf, binary.LittleEndian, int32(10+len(packet.Payload))); err != nil {
+		return err
+	}
+
+	// Request ID - int32
+	if err := binary.Write(buf, binary.LittleEndian, packet.RequestID); err != nil {
+		return err
+	}
+
+	// Type - int32
+	if err := binary.Write(buf, binary.LittleEndian, packet.Type); err != nil {
+		return err
+	}
+
+	// Payload - null-terminated string
+	if _, err := buf.Write(append([]byte(packet.Payload), 0x00)); err != nil {
+		return err
+	}
+
+	// Padding - byte
+	if err := buf.WriteByte(0x00); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// maxPacketSize returns the max packet size of the options, or the default of the mode if it is not set
+func (o RCONOptions) maxPacketSize() int {
+	if o.MaxPacketSize > 0 {
+		return o.MaxPacketSize
+	}
+
+	if o.Mode == RCONModeSource {
+		return 4096
+	}
+
+	return 1 << 16
 }
 
 func parseRCONOptions(opts ...RCONOptions) RCONOptions {
@@ -322,5 +601,15 @@ func parseRCONOptions(opts ...RCONOptions) RCONOptions {
 		return defaultRCONOptions
 	}
 
-	return opts[0]
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultRCONOptions.Timeout
+	}
+
+	if options.MaxPacketSize <= 0 {
+		options.MaxPacketSize = options.maxPacketSize()
+	}
+
+	return options
 }
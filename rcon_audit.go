@@ -0,0 +1,305 @@
+package mcstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	defaultRCONAuditLogOptions = RCONAuditLogOptions{
+		MaxSize:           10 * 1024 * 1024,
+		MaxBackups:        5,
+		MaxResponseLength: 4096,
+	}
+)
+
+// RCONCaller identifies who a command was run on behalf of, as supplied by the application. The role is used
+// to look up the rules of the RCON policy that apply to the caller.
+type RCONCaller struct {
+	Identity string `json:"identity"`
+	Role     string `json:"role,omitempty"`
+}
+
+// RCONAuditRecord is a single command run over RCON. Logging in is never recorded, so passwords never appear
+// in the audit log.
+type RCONAuditRecord struct {
+	Time         time.Time `json:"time"`
+	Caller       string    `json:"caller"`
+	Role         string    `json:"role,omitempty"`
+	Server       string    `json:"server"`
+	Command      string    `json:"command"`
+	DurationMS   float64   `json:"duration_ms"`
+	ResponseSize int       `json:"response_size"`
+	Response     string    `json:"response,omitempty"`
+	Truncated    bool      `json:"truncated,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// RCONAuditor records the commands run by RCON clients, it must be safe for concurrent use
+type RCONAuditor interface {
+	Record(record RCONAuditRecord) error
+}
+
+// RCONAuditError is returned along with the output of a command that was run, but could not be recorded by the
+// auditor, such as when the disk of the audit log is full
+type RCONAuditError struct {
+	Command string
+	Err     error
+}
+
+func (e *RCONAuditError) Error() string {
+	return fmt.Sprintf("command %q was run but could not be recorded in the audit log: %s", e.Command, e.Err)
+}
+
+func (e *RCONAuditError) Unwrap() error {
+	return e.Err
+}
+
+type RCONAuditLogOptions struct {
+	MaxSize           int64
+	MaxBackups        int
+	MaxResponseLength int
+}
+
+// RCONAuditLog is an RCONAuditor that appends each record to a file as a line of JSON. Once the file would
+// grow beyond the max size it is renamed with a ".1" suffix, shifting any older files up to the max number
+// of backups, and a new file is started, or the file is started over if the max backups is 0. Responses longer
+// than the max response length are truncated, but the response size is always the full size.
+type RCONAuditLog struct {
+	path    string
+	options RCONAuditLogOptions
+	file    *os.File
+	size    int64
+	lock    sync.Mutex
+}
+
+// NewRCONAuditLog opens the audit log at the path, appending to it if it already exists
+func NewRCONAuditLog(path string, options ...RCONAuditLogOptions) (*RCONAuditLog, error) {
+	l := &RCONAuditLog{
+		path:    path,
+		options: parseRCONAuditLogOptions(options...),
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Record appends the record to the log, rotating the file first if it would exceed the max size
+func (l *RCONAuditLog) Record(record RCONAuditRecord) error {
+	if len(record.Response) > l.options.MaxResponseLength {
+		end := l.options.MaxResponseLength
+
+		// Avoid splitting a character, which would be replaced when encoded as JSON
+		for end > 0 && !utf8.RuneStart(record.Response[end]) {
+			end--
+		}
+
+		record.Response = record.Response[:end]
+		record.Truncated = true
+	}
+
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return ErrServerClosed
+	}
+
+	if l.size > 0 && l.size+int64(len(data)) > l.options.MaxSize {
+		if err = l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(data)
+
+	l.size += int64(n)
+
+	return err
+}
+
+// Close closes the file of the audit log
+func (l *RCONAuditLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return ErrServerClosed
+	}
+
+	err := l.file.Close()
+
+	l.file = nil
+
+	return err
+}
+
+func (l *RCONAuditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+
+	return nil
+}
+
+// rotate moves the current file to the first backup and opens a new file, the log must be locked
+func (l *RCONAuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	l.file = nil
+
+	if l.options.MaxBackups < 1 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return l.open()
+	}
+
+	for i := l.options.MaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(l.path, l.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return l.open()
+}
+
+// ExecuteAs is the same as Execute, but checks the command against the policy for the caller and records the
+// caller in the audit log of the client
+func (r *RCON) ExecuteAs(caller RCONCaller, command string) (string, error) {
+	return r.executeAudited(caller, command, r.options.Timeout)
+}
+
+// RunAs is the same as Run, but checks the command against the policy for the caller and records the caller
+// in the audit log of the client. The output of the command is not known, so only the command and any error
+// sending it are recorded.
+func (r *RCON) RunAs(caller RCONCaller, command string) error {
+	start := time.Now()
+
+	if err := r.checkPolicy(caller, command); err != nil {
+		r.audit(caller, command, start, "", err)
+
+		return err
+	}
+
+	err := r.run(command)
+
+	if auditErr := r.audit(caller, command, start, "", err); auditErr != nil && err == nil {
+		err = auditErr
+	}
+
+	return err
+}
+
+// executeAudited runs the command if the policy allows it, and records it in the audit log if the client has one.
+// A command that could not be recorded returns an RCONAuditError, unless it already failed, so a broken audit
+// log is noticed rather than silently ignored. A denied command was not run, so it is denied either way.
+func (r *RCON) executeAudited(caller RCONCaller, command string, timeout time.Duration) (string, error) {
+	start := time.Now()
+
+	if err := r.checkPolicy(caller, command); err != nil {
+		r.audit(caller, command, start, "", err)
+
+		return "", err
+	}
+
+	output, err := r.execute(command, false, timeout)
+
+	if auditErr := r.audit(caller, command, start, output, err); auditErr != nil && err == nil {
+		err = auditErr
+	}
+
+	return output, err
+}
+
+// audit records the command with the auditor of the client, returning an RCONAuditError if it could not be
+func (r *RCON) audit(caller RCONCaller, command string, start time.Time, output string, err error) error {
+	if r.options.Auditor == nil {
+		return nil
+	}
+
+	record := RCONAuditRecord{
+		Time:         start.UTC(),
+		Caller:       caller.Identity,
+		Role:         caller.Role,
+		Server:       r.address,
+		Command:      command,
+		DurationMS:   float64(time.Since(start)) / float64(time.Millisecond),
+		ResponseSize: len(output),
+		Response:     output,
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if auditErr := r.options.Auditor.Record(record); auditErr != nil {
+		return &RCONAuditError{
+			Command: command,
+			Err:     auditErr,
+		}
+	}
+
+	return nil
+}
+
+func (r *RCON) checkPolicy(caller RCONCaller, command string) error {
+	if r.options.Policy == nil {
+		return nil
+	}
+
+	return r.options.Policy.Check(caller, command)
+}
+
+func parseRCONAuditLogOptions(opts ...RCONAuditLogOptions) RCONAuditLogOptions {
+	if len(opts) < 1 {
+		return defaultRCONAuditLogOptions
+	}
+
+	options := opts[0]
+
+	if options.MaxSize <= 0 {
+		options.MaxSize = defaultRCONAuditLogOptions.MaxSize
+	}
+
+	if options.MaxResponseLength <= 0 {
+		options.MaxResponseLength = defaultRCONAuditLogOptions.MaxResponseLength
+	}
+
+	return options
+}
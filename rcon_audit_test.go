@@ -0,0 +1,185 @@
+package mcstatus_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func readTestAuditRecords(t *testing.T, path string) []mcstatus.RCONAuditRecord {
+	f, err := os.Open(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	records := make([]mcstatus.RCONAuditRecord, 0)
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		var record mcstatus.RCONAuditRecord
+
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestRCONAuditLog(t *testing.T) {
+	port := startTestRCON(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log, err := mcstatus.NewRCONAuditLog(path, mcstatus.RCONAuditLogOptions{MaxSize: 1024, MaxBackups: 2, MaxResponseLength: 16})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := mcstatus.NewRCON()
+
+	if err = client.Dial("127.0.0.1", port, mcstatus.RCONOptions{Timeout: time.Second * 5, Auditor: log}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err = client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.ExecuteAs(mcstatus.RCONCaller{Identity: "alice"}, "say Hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Execute("long"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readTestAuditRecords(t, path)
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	if records[0].Caller != "alice" || records[0].Command != "say Hi" || records[0].Response != "say Hi" || !strings.HasPrefix(records[0].Server, "127.0.0.1:") {
+		t.Fatalf("unexpected record: %+v", records[0])
+	}
+
+	if records[1].ResponseSize != 10000 || len(records[1].Response) != 16 || !records[1].Truncated {
+		t.Fatalf("unexpected record: %+v", records[1])
+	}
+
+	// Each record is about 200 bytes, so the log is rotated several times and only two backups are kept
+	for i := 0; i < 30; i++ {
+		if _, err = client.ExecuteAs(mcstatus.RCONCaller{Identity: "bob"}, "list"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Size() > 1024 {
+			t.Fatalf("expected %s to be rotated, got %d bytes", name, info.Size())
+		}
+
+		data, err := os.ReadFile(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(string(data), "password") {
+			t.Fatalf("password found in %s", name)
+		}
+	}
+
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected only two backups, got %v", err)
+	}
+}
+
+// failingAuditor fails to record every command, as if the disk of the audit log was full
+type failingAuditor struct {
+	err error
+}
+
+func (a failingAuditor) Record(record mcstatus.RCONAuditRecord) error {
+	return a.err
+}
+
+func TestRCONAuditError(t *testing.T) {
+	port := startTestRCON(t)
+	diskFull := errors.New("no space left on device")
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", port, mcstatus.RCONOptions{Timeout: time.Second * 5, Auditor: failingAuditor{err: diskFull}}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command was run, so its output is returned along with the error
+	output, err := client.ExecuteAs(mcstatus.RCONCaller{Identity: "alice"}, "say Hi")
+
+	var auditErr *mcstatus.RCONAuditError
+
+	if !errors.As(err, &auditErr) || !errors.Is(err, diskFull) || auditErr.Command != "say Hi" || output != "say Hi" {
+		t.Fatalf("unexpected result: %q, %v", output, err)
+	}
+
+	if err = client.RunAs(mcstatus.RCONCaller{Identity: "alice"}, "list"); !errors.Is(err, diskFull) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRCONAuditLogDefaultOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// Only the backups are set, every other option uses its default
+	log, err := mcstatus.NewRCONAuditLog(path, mcstatus.RCONAuditLogOptions{MaxBackups: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = log.Record(mcstatus.RCONAuditRecord{Command: "long", Response: strings.Repeat("a", 5000), ResponseSize: 5000}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readTestAuditRecords(t, path)
+
+	if len(records) != 1 || len(records[0].Response) != 4096 || !records[0].Truncated {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
@@ -0,0 +1,172 @@
+package mcstatus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	defaultRCONBackupOptions = RCONBackupOptions{
+		SaveTimeout: time.Minute * 5,
+	}
+)
+
+// RCONSnapshotFunc takes a snapshot of the world directory, such as compressing it into an archive, while the
+// server is not writing to it
+type RCONSnapshotFunc func() error
+
+// RCONBackupOptions are the options of a backup. The save timeout is how long to wait for the server to save
+// the world, which can take minutes for large worlds. The caller is used for the policy and audit log of the
+// client.
+type RCONBackupOptions struct {
+	SaveTimeout time.Duration
+	Caller      RCONCaller
+}
+
+// RCONBackupResult is the outcome of a backup, the durations of steps that did not run are 0
+type RCONBackupResult struct {
+	Started          time.Time     `json:"started"`
+	Duration         time.Duration `json:"duration"`
+	SaveDuration     time.Duration `json:"save_duration"`
+	SnapshotDuration time.Duration `json:"snapshot_duration"`
+	Success          bool          `json:"success"`
+	Error            string        `json:"error,omitempty"`
+	Err              error         `json:"-"`
+}
+
+// RCONBackupError is returned when a step of a backup failed, the step is one of save-off, save-all, snapshot
+// or save-on
+type RCONBackupError struct {
+	Step string
+	Err  error
+}
+
+func (e *RCONBackupError) Error() string {
+	return fmt.Sprintf("backup failed at %s: %s", e.Step, e.Err)
+}
+
+func (e *RCONBackupError) Unwrap() error {
+	return e.Err
+}
+
+// Backup takes a backup of the world without the server writing to it. Automatic saving is turned off, the
+// world is saved and flushed to disk, and the snapshot is taken once the output of the save command reports
+// that the game was saved, otherwise an RCONCommandError with the output and ErrGameNotSaved is returned. Saving is turned back on afterwards, even if a step failed or the snapshot panicked, unless it was
+// already turned off before the backup started.
+func (r *RCON) Backup(snapshot RCONSnapshotFunc, options ...RCONBackupOptions) (result *RCONBackupResult, err error) {
+	opts := parseRCONBackupOptions(options...)
+
+	// The client may not have been dialed with options, in which case its timeout is not set
+	timeout := r.options.Timeout
+
+	if timeout <= 0 {
+		timeout = defaultRCONOptions.Timeout
+	}
+
+	result = &RCONBackupResult{
+		Started: time.Now(),
+	}
+
+	wasOff := false
+
+	defer (func() {
+		// A failure to turn saving back on is reported even if the backup succeeded, as the server would
+		// otherwise stop saving the world without anyone noticing
+		if !wasOff {
+			if saveErr := r.backupCommand(opts.Caller, "save-on", timeout); saveErr != nil && !errors.Is(saveErr, ErrNothingChanged) && err == nil {
+				err = &RCONBackupError{Step: "save-on", Err: saveErr}
+			}
+		}
+
+		result.Duration = time.Since(result.Started)
+		result.Success = err == nil
+		result.Err = err
+
+		if err != nil {
+			result.Error = err.Error()
+		}
+	})()
+
+	// Saving that was turned off by someone else is left off once the backup is done
+	if err = r.backupCommand(opts.Caller, "save-off", timeout); errors.Is(err, ErrNothingChanged) {
+		wasOff = true
+		err = nil
+	} else if err != nil {
+		return result, &RCONBackupError{Step: "save-off", Err: err}
+	}
+
+	start := time.Now()
+
+	if err = r.backupSave(opts); err != nil {
+		return result, &RCONBackupError{Step: "save-all", Err: err}
+	}
+
+	result.SaveDuration = time.Since(start)
+
+	start = time.Now()
+
+	err = snapshot()
+
+	result.SnapshotDuration = time.Since(start)
+
+	if err != nil {
+		return result, &RCONBackupError{Step: "snapshot", Err: err}
+	}
+
+	return result, nil
+}
+
+// backupSave saves the world and flushes it to disk, the server only responds once the save is done and
+// reports that the game was saved if it finished
+func (r *RCON) backupSave(opts RCONBackupOptions) error {
+	output, err := r.executeAudited(opts.Caller, "save-all flush", opts.SaveTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	if err = CheckRCONOutput("save-all flush", output); err != nil {
+		return err
+	}
+
+	if !isRCONSaveComplete(output) {
+		return &RCONCommandError{
+			Command: "save-all flush",
+			Output:  output,
+			Err:     ErrGameNotSaved,
+		}
+	}
+
+	return nil
+}
+
+// backupCommand runs a command that changes whether the server saves automatically
+func (r *RCON) backupCommand(caller RCONCaller, command string, timeout time.Duration) error {
+	output, err := r.executeAudited(caller, command, timeout)
+
+	if err != nil {
+		return err
+	}
+
+	return CheckRCONOutput(command, output)
+}
+
+func isRCONSaveComplete(output string) bool {
+	return strings.Contains(strings.ToLower(output), "saved the game")
+}
+
+func parseRCONBackupOptions(opts ...RCONBackupOptions) RCONBackupOptions {
+	if len(opts) < 1 {
+		return defaultRCONBackupOptions
+	}
+
+	options := opts[0]
+
+	if options.SaveTimeout <= 0 {
+		options.SaveTimeout = defaultRCONBackupOptions.SaveTimeout
+	}
+
+	return options
+}
@@ -0,0 +1,198 @@
+package mcstatus_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+// testBackupServer is an RCON server that responds to the save commands the same as a vanilla server
+type testBackupServer struct {
+	commands []string
+	saved    bool
+	off      bool
+	lock     sync.Mutex
+}
+
+func (s *testBackupServer) handle(addr net.Addr, command string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.commands = append(s.commands, command)
+
+	switch command {
+	case "save-off":
+		{
+			if s.off {
+				return "Saving is already turned off"
+			}
+
+			s.off = true
+
+			return "Automatic saving is now disabled"
+		}
+	case "save-on":
+		{
+			if !s.off {
+				return "Saving is already turned on"
+			}
+
+			s.off = false
+
+			return "Automatic saving is now enabled"
+		}
+	case "save-all flush":
+		{
+			if !s.saved {
+				return "Saving the game (this may take a moment!)"
+			}
+
+			return "Saving the game (this may take a moment!)Saved the game"
+		}
+	}
+
+	return "Unknown or incomplete command, see below for error"
+}
+
+func (s *testBackupServer) reset() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	commands := s.commands
+	s.commands = nil
+
+	return commands
+}
+
+func TestRCONBackup(t *testing.T) {
+	backupServer := &testBackupServer{saved: true}
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", backupServer.handle)
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	client := mcstatus.NewRCON()
+
+	if err = client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err = client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"save-off", "save-all flush", "save-on"}
+
+	// The snapshot is taken while saving is turned off
+	result, err := client.Backup(func() error {
+		if commands := backupServer.reset(); !reflect.DeepEqual(commands, expected[:2]) {
+			t.Errorf("unexpected commands before the snapshot: %v", commands)
+		}
+
+		time.Sleep(time.Millisecond * 10)
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Success || result.SnapshotDuration < time.Millisecond*10 || result.Duration < result.SnapshotDuration {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	if commands := backupServer.reset(); !reflect.DeepEqual(commands, expected[2:]) {
+		t.Fatalf("unexpected commands after the snapshot: %v", commands)
+	}
+
+	// Saving is turned back on when the snapshot fails
+	snapshotErr := errors.New("disk full")
+
+	result, err = client.Backup(func() error {
+		return snapshotErr
+	})
+
+	var backupErr *mcstatus.RCONBackupError
+
+	if !errors.As(err, &backupErr) || backupErr.Step != "snapshot" || !errors.Is(err, snapshotErr) || result.Success || result.Err != err {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if commands := backupServer.reset(); !reflect.DeepEqual(commands, expected) {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+
+	// Saving is turned back on when the snapshot panics
+	(func() {
+		defer (func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to be passed on")
+			}
+		})()
+
+		client.Backup(func() error {
+			panic("snapshot failed")
+		})
+	})()
+
+	if commands := backupServer.reset(); !reflect.DeepEqual(commands, expected) {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+
+	// The snapshot is not taken if the server does not report that the game was saved
+	backupServer.lock.Lock()
+	backupServer.saved = false
+	backupServer.lock.Unlock()
+
+	_, err = client.Backup(func() error {
+		t.Error("snapshot taken before the game was saved")
+
+		return nil
+	}, mcstatus.RCONBackupOptions{SaveTimeout: time.Millisecond * 100})
+
+	var commandErr *mcstatus.RCONCommandError
+
+	if !errors.As(err, &backupErr) || backupErr.Step != "save-all" || !errors.Is(err, mcstatus.ErrGameNotSaved) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !errors.As(err, &commandErr) || commandErr.Output != "Saving the game (this may take a moment!)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if commands := backupServer.reset(); !reflect.DeepEqual(commands, expected) {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+
+	// Saving that was already turned off is left off
+	backupServer.lock.Lock()
+	backupServer.saved = true
+	backupServer.off = true
+	backupServer.lock.Unlock()
+
+	if result, err = client.Backup(func() error {
+		return nil
+	}); err != nil || !result.Success {
+		t.Fatalf("unexpected result: %+v, %v", result, err)
+	}
+
+	if commands := backupServer.reset(); !reflect.DeepEqual(commands, expected[:2]) {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+}
@@ -0,0 +1,138 @@
+package mcstatus
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	defaultRCONBroadcastOptions = RCONBroadcastOptions{
+		Concurrency: 8,
+	}
+)
+
+// RCONFailurePolicy is what a broadcast does when the command fails on one of the servers
+type RCONFailurePolicy int
+
+const (
+	// RCONBestEffort runs the command on every server, no matter how many fail
+	RCONBestEffort RCONFailurePolicy = iota
+	// RCONAbortOnError stops sending the command to more servers once it fails on any server. Commands that
+	// were already sent are not cancelled, and their results are still returned.
+	RCONAbortOnError
+)
+
+// RCONBroadcastOptions are the options of a broadcast. The command is sent to every server in the pool if no
+// servers are given, to at most the concurrency servers at a time, which defaults to 8. The timeout is for each
+// server, and defaults to the command timeout of the pool.
+type RCONBroadcastOptions struct {
+	Servers       []string
+	Concurrency   int
+	Timeout       time.Duration
+	FailurePolicy RCONFailurePolicy
+	Caller        RCONCaller
+}
+
+// RCONBroadcastResult is the result of a broadcast command on a single server
+type RCONBroadcastResult struct {
+	Server  string        `json:"server"`
+	Output  string        `json:"output"`
+	Latency time.Duration `json:"latency"`
+	Err     error         `json:"-"`
+}
+
+// Broadcast runs the command on many servers in the pool at the same time, and returns the result of each
+// server in the same order as the servers, or in alphabetical order if every server was used. With the
+// RCONAbortOnError policy the error of the first server that failed is returned, and servers that the command
+// was not sent to have the error ErrBroadcastAborted. With the RCONBestEffort policy the error is always nil,
+// and the error of each server is in its result.
+func (p *RCONPool) Broadcast(command string, options ...RCONBroadcastOptions) ([]RCONBroadcastResult, error) {
+	opts := parseRCONBroadcastOptions(options...)
+
+	servers := opts.Servers
+
+	if len(servers) < 1 {
+		servers = p.Servers()
+	}
+
+	timeout := opts.Timeout
+
+	if timeout <= 0 {
+		timeout = p.options.CommandTimeout
+	}
+
+	concurrency := opts.Concurrency
+
+	if concurrency > len(servers) {
+		concurrency = len(servers)
+	}
+
+	results := make([]RCONBroadcastResult, len(servers))
+	semaphore := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	lock := &sync.Mutex{}
+
+	var firstErr error
+
+	for i, server := range servers {
+		results[i].Server = server
+
+		semaphore <- struct{}{}
+
+		lock.Lock()
+		aborted := firstErr != nil && opts.FailurePolicy == RCONAbortOnError
+		lock.Unlock()
+
+		if aborted {
+			<-semaphore
+
+			results[i].Err = ErrBroadcastAborted
+
+			continue
+		}
+
+		wg.Add(1)
+
+		go (func(result *RCONBroadcastResult) {
+			defer wg.Done()
+			defer (func() { <-semaphore })()
+
+			start := time.Now()
+
+			result.Output, result.Err = p.executeAs(opts.Caller, result.Server, command, timeout)
+			result.Latency = time.Since(start)
+
+			if result.Err != nil {
+				lock.Lock()
+
+				if firstErr == nil {
+					firstErr = result.Err
+				}
+
+				lock.Unlock()
+			}
+		})(&results[i])
+	}
+
+	wg.Wait()
+
+	if opts.FailurePolicy == RCONAbortOnError {
+		return results, firstErr
+	}
+
+	return results, nil
+}
+
+func parseRCONBroadcastOptions(opts ...RCONBroadcastOptions) RCONBroadcastOptions {
+	if len(opts) < 1 {
+		return defaultRCONBroadcastOptions
+	}
+
+	options := opts[0]
+
+	if options.Concurrency < 1 {
+		options.Concurrency = defaultRCONBroadcastOptions.Concurrency
+	}
+
+	return options
+}
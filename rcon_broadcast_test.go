@@ -0,0 +1,180 @@
+package mcstatus_test
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestRCONPoolBroadcast(t *testing.T) {
+	var running, maxRunning int32
+
+	handler := func(addr net.Addr, command string) string {
+		n := atomic.AddInt32(&running, 1)
+
+		for {
+			current := atomic.LoadInt32(&maxRunning)
+
+			if n <= current || atomic.CompareAndSwapInt32(&maxRunning, current, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 50)
+
+		atomic.AddInt32(&running, -1)
+
+		return command
+	}
+
+	pool := mcstatus.NewRCONPool(mcstatus.RCONPoolOptions{
+		RCONOptions:    mcstatus.RCONOptions{Timeout: time.Second},
+		Connections:    1,
+		CommandTimeout: time.Second,
+		MinBackoff:     time.Second,
+		MaxBackoff:     time.Second,
+	})
+
+	defer pool.Close()
+
+	for i := 0; i < 4; i++ {
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server := mcstatus.NewRCONServer("password", handler)
+
+		go server.Serve(l)
+
+		defer server.Close()
+
+		if err = pool.Add("server"+strconv.Itoa(i), "127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), "password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A server that is down
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Close()
+
+	if err = pool.Add("down", "127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := pool.Broadcast("whitelist reload", mcstatus.RCONBroadcastOptions{
+		Servers:     []string{"server0", "server1", "server2", "server3"},
+		Concurrency: 2,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, result := range results {
+		if result.Server != "server"+strconv.Itoa(i) || result.Err != nil || result.Output != "whitelist reload" || result.Latency < time.Millisecond*50 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	}
+
+	if n := atomic.LoadInt32(&maxRunning); n != 2 {
+		t.Fatalf("expected at most 2 commands at a time, got %d", n)
+	}
+
+	// Best effort runs on every server, even though one of them is down
+	results, err = pool.Broadcast("say Hi")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 5 || results[0].Server != "down" || results[0].Err == nil || results[1].Err != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	// Aborting stops at the server that is down
+	results, err = pool.Broadcast("say Hi", mcstatus.RCONBroadcastOptions{
+		Servers:       []string{"down", "server0", "server1", "unknown"},
+		Concurrency:   1,
+		FailurePolicy: mcstatus.RCONAbortOnError,
+	})
+
+	if err == nil || err != results[0].Err {
+		t.Fatalf("expected the error of the first server, got %v", err)
+	}
+
+	for _, result := range results[1:] {
+		if !errors.Is(result.Err, mcstatus.ErrBroadcastAborted) {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	}
+
+	// Unknown servers fail like any other server
+	results, _ = pool.Broadcast("say Hi", mcstatus.RCONBroadcastOptions{Servers: []string{"unknown"}})
+
+	if results[0].Err != mcstatus.ErrUnknownServer {
+		t.Fatalf("unexpected result: %+v", results[0])
+	}
+}
+
+func TestRCONPoolBroadcastDefaultConcurrency(t *testing.T) {
+	var running, maxRunning int32
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		n := atomic.AddInt32(&running, 1)
+
+		for {
+			current := atomic.LoadInt32(&maxRunning)
+
+			if n <= current || atomic.CompareAndSwapInt32(&maxRunning, current, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 50)
+
+		atomic.AddInt32(&running, -1)
+
+		return command
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	pool := mcstatus.NewRCONPool()
+
+	defer pool.Close()
+
+	for i := 0; i < 12; i++ {
+		if err = pool.Add("server"+strconv.Itoa(i), "127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), "password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the failure policy is set, so the default concurrency limits the commands sent at a time
+	if _, err = pool.Broadcast("say Hi", mcstatus.RCONBroadcastOptions{FailurePolicy: mcstatus.RCONAbortOnError}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(&maxRunning); n != 8 {
+		t.Fatalf("expected at most 8 commands at a time, got %d", n)
+	}
+}
@@ -0,0 +1,481 @@
+package mcstatus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	rconListRegExp          = regexp.MustCompile(`(?s)^There (?:are|is) (\d+) ?(?:of a max of|out of maximum|/) ?(\d+) players? online[:.]?(.*)$`)
+	rconListPlayerRegExp    = regexp.MustCompile(`^(\S+)(?: \(([0-9a-fA-F-]{32,36})\))?$`)
+	rconWhitelistRegExp     = regexp.MustCompile(`(?s)^There (?:are|is) (\d+) whitelisted players?:(.*)$`)
+	rconBanlistRegExp       = regexp.MustCompile(`(?s)^There (?:are|is) (\d+) bans?(?:\(s\))?:(.*)$`)
+	rconBanRegExp           = regexp.MustCompile(`([A-Za-z0-9_]{1,16}|\d{1,3}(?:\.\d{1,3}){3}) was banned by ([^:\n]+?): `)
+	rconTimeRegExp          = regexp.MustCompile(`^The time is (\d+)`)
+	rconDifficultyRegExp    = regexp.MustCompile(`^The difficulty is (\w+)`)
+	rconSetDifficultyRegExp = regexp.MustCompile(`^The difficulty has been set to (\w+)`)
+	rconSeedRegExp          = regexp.MustCompile(`Seed: \[(-?\d+)\]`)
+	rconWorldBorderRegExp   = regexp.MustCompile(`^The world border is currently (\d+(?:\.\d+)?) blocks?(?:\(s\))? wide`)
+	rconPlayerNameRegExp    = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+	// rconErrorPrefixes maps the start of the error messages of a vanilla server to the error they represent
+	rconErrorPrefixes = []struct {
+		prefix string
+		err    error
+	}{
+		{"Unknown or incomplete command", ErrUnknownCommand},
+		{"Unknown command", ErrUnknownCommand},
+		{"Incorrect argument for command", ErrInvalidArgument},
+		{"Invalid ", ErrInvalidArgument},
+		{"Expected ", ErrInvalidArgument},
+		{"That player does not exist", ErrPlayerNotFound},
+		{"No player was found", ErrPlayerNotFound},
+		{"No entity was found", ErrEntityNotFound},
+		{"Found no elements matching ", ErrEntityNotFound},
+		{"Nothing changed", ErrNothingChanged},
+		{"Player is already whitelisted", ErrNothingChanged},
+		{"Player is not whitelisted", ErrNothingChanged},
+		{"The difficulty did not change", ErrNothingChanged},
+		{"Saving is already turned off", ErrNothingChanged},
+		{"Saving is already turned on", ErrNothingChanged},
+		{"An unexpected error occurred", ErrCommandFailed},
+	}
+)
+
+// RCONCommandError is returned by the typed command helpers when the server responded with an error, or with
+// output that could not be parsed. Err is one of the RCON command errors, such as ErrPlayerNotFound.
+type RCONCommandError struct {
+	Command string
+	Output  string
+	Err     error
+}
+
+func (e *RCONCommandError) Error() string {
+	return fmt.Sprintf("command %q failed: %s: %s", e.Command, e.Err, e.Output)
+}
+
+func (e *RCONCommandError) Unwrap() error {
+	return e.Err
+}
+
+// RCONPlayer is a player in the output of the list command, the UUID is only known if it was requested
+type RCONPlayer struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid,omitempty"`
+}
+
+// RCONPlayerList is the output of the list command
+type RCONPlayerList struct {
+	Online  int          `json:"online"`
+	Max     int          `json:"max"`
+	Players []RCONPlayer `json:"players"`
+}
+
+// RCONBan is a single entry of the ban list, the target is either a player name or an IP address
+type RCONBan struct {
+	Target string `json:"target"`
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// CheckRCONOutput returns an RCONCommandError if the output of the command is one of the error messages of a
+// vanilla server, or nil otherwise
+func CheckRCONOutput(command, output string) error {
+	for _, v := range rconErrorPrefixes {
+		if strings.HasPrefix(output, v.prefix) {
+			return &RCONCommandError{
+				Command: command,
+				Output:  output,
+				Err:     v.err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// List returns the players that are online, along with their UUIDs if uuids is true
+func (r *RCON) List(uuids bool) (*RCONPlayerList, error) {
+	return r.ListAs(RCONCaller{}, uuids)
+}
+
+// ListAs is the same as List, but runs the command as the caller
+func (r *RCON) ListAs(caller RCONCaller, uuids bool) (*RCONPlayerList, error) {
+	command := "list"
+
+	if uuids {
+		command = "list uuids"
+	}
+
+	output, err := r.executeChecked(caller, command)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRCONPlayerList(command, output)
+}
+
+// parseRCONPlayerList parses the output of the list command
+func parseRCONPlayerList(command, output string) (*RCONPlayerList, error) {
+	match := rconListRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return nil, unexpectedRCONOutput(command, output)
+	}
+
+	list := &RCONPlayerList{
+		Players: make([]RCONPlayer, 0),
+	}
+
+	list.Online, _ = strconv.Atoi(match[1])
+	list.Max, _ = strconv.Atoi(match[2])
+
+	for _, name := range splitRCONNames(match[3]) {
+		player := RCONPlayer{
+			Name: name,
+		}
+
+		if m := rconListPlayerRegExp.FindStringSubmatch(name); m != nil {
+			player.Name = m[1]
+			player.UUID = m[2]
+		}
+
+		list.Players = append(list.Players, player)
+	}
+
+	return list, nil
+}
+
+// WhitelistList returns the names of the whitelisted players
+func (r *RCON) WhitelistList() ([]string, error) {
+	return r.WhitelistListAs(RCONCaller{})
+}
+
+// WhitelistListAs is the same as WhitelistList, but runs the command as the caller
+func (r *RCON) WhitelistListAs(caller RCONCaller) ([]string, error) {
+	output, err := r.executeChecked(caller, "whitelist list")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(output, "There are no whitelisted players") {
+		return make([]string, 0), nil
+	}
+
+	match := rconWhitelistRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return nil, unexpectedRCONOutput("whitelist list", output)
+	}
+
+	return splitRCONNames(match[2]), nil
+}
+
+// WhitelistAdd adds the player to the whitelist, ErrNothingChanged is returned if they already were
+func (r *RCON) WhitelistAdd(player string) error {
+	return r.WhitelistAddAs(RCONCaller{}, player)
+}
+
+// WhitelistAddAs is the same as WhitelistAdd, but runs the command as the caller
+func (r *RCON) WhitelistAddAs(caller RCONCaller, player string) error {
+	return r.executePlayerCommand(caller, "whitelist add", player, "Added ")
+}
+
+// WhitelistRemove removes the player from the whitelist, ErrNothingChanged is returned if they were not on it
+func (r *RCON) WhitelistRemove(player string) error {
+	return r.WhitelistRemoveAs(RCONCaller{}, player)
+}
+
+// WhitelistRemoveAs is the same as WhitelistRemove, but runs the command as the caller
+func (r *RCON) WhitelistRemoveAs(caller RCONCaller, player string) error {
+	return r.executePlayerCommand(caller, "whitelist remove", player, "Removed ")
+}
+
+// Banlist returns the banned players and IP addresses. Vanilla servers send each ban as a separate message
+// without a separator between them, so a reason that ends in a letter or digit may run into the next entry.
+func (r *RCON) Banlist() ([]RCONBan, error) {
+	return r.BanlistAs(RCONCaller{})
+}
+
+// BanlistAs is the same as Banlist, but runs the command as the caller
+func (r *RCON) BanlistAs(caller RCONCaller) ([]RCONBan, error) {
+	output, err := r.executeChecked(caller, "banlist")
+
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make([]RCONBan, 0)
+
+	if strings.HasPrefix(output, "There are no bans") {
+		return bans, nil
+	}
+
+	match := rconBanlistRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return nil, unexpectedRCONOutput("banlist", output)
+	}
+
+	entries := match[2]
+	indices := rconBanRegExp.FindAllStringSubmatchIndex(entries, -1)
+
+	for k, index := range indices {
+		end := len(entries)
+
+		if k+1 < len(indices) {
+			end = indices[k+1][0]
+		}
+
+		bans = append(bans, RCONBan{
+			Target: entries[index[2]:index[3]],
+			Source: entries[index[4]:index[5]],
+			Reason: strings.TrimSpace(entries[index[1]:end]),
+		})
+	}
+
+	return bans, nil
+}
+
+// Op makes the player a server operator, ErrNothingChanged is returned if they already were
+func (r *RCON) Op(player string) error {
+	return r.OpAs(RCONCaller{}, player)
+}
+
+// OpAs is the same as Op, but runs the command as the caller
+func (r *RCON) OpAs(caller RCONCaller, player string) error {
+	return r.executePlayerCommand(caller, "op", player, "Made ")
+}
+
+// Deop makes the player no longer a server operator, ErrNothingChanged is returned if they were not one
+func (r *RCON) Deop(player string) error {
+	return r.DeopAs(RCONCaller{}, player)
+}
+
+// DeopAs is the same as Deop, but runs the command as the caller
+func (r *RCON) DeopAs(caller RCONCaller, player string) error {
+	return r.executePlayerCommand(caller, "deop", player, "Made ")
+}
+
+// QueryTime returns the time of the world, the query is one of daytime, gametime or day, ErrInvalidArgument is
+// returned for any other query
+func (r *RCON) QueryTime(query string) (int64, error) {
+	return r.QueryTimeAs(RCONCaller{}, query)
+}
+
+// QueryTimeAs is the same as QueryTime, but runs the command as the caller
+func (r *RCON) QueryTimeAs(caller RCONCaller, query string) (int64, error) {
+	if !isRCONArgument(query, "daytime", "gametime", "day") {
+		return 0, ErrInvalidArgument
+	}
+
+	command := "time query " + query
+
+	output, err := r.executeChecked(caller, command)
+
+	if err != nil {
+		return 0, err
+	}
+
+	match := rconTimeRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return 0, unexpectedRCONOutput(command, output)
+	}
+
+	return strconv.ParseInt(match[1], 10, 64)
+}
+
+// Difficulty returns the difficulty of the world, such as Normal
+func (r *RCON) Difficulty() (string, error) {
+	return r.DifficultyAs(RCONCaller{})
+}
+
+// DifficultyAs is the same as Difficulty, but runs the command as the caller
+func (r *RCON) DifficultyAs(caller RCONCaller) (string, error) {
+	output, err := r.executeChecked(caller, "difficulty")
+
+	if err != nil {
+		return "", err
+	}
+
+	match := rconDifficultyRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return "", unexpectedRCONOutput("difficulty", output)
+	}
+
+	return match[1], nil
+}
+
+// SetDifficulty sets the difficulty of the world to peaceful, easy, normal or hard, ErrNothingChanged is returned
+// if it already was the difficulty and ErrInvalidArgument is returned for any other difficulty
+func (r *RCON) SetDifficulty(difficulty string) error {
+	return r.SetDifficultyAs(RCONCaller{}, difficulty)
+}
+
+// SetDifficultyAs is the same as SetDifficulty, but runs the command as the caller
+func (r *RCON) SetDifficultyAs(caller RCONCaller, difficulty string) error {
+	if !isRCONArgument(difficulty, "peaceful", "easy", "normal", "hard") {
+		return ErrInvalidArgument
+	}
+
+	command := "difficulty " + difficulty
+
+	output, err := r.executeChecked(caller, command)
+
+	if err != nil {
+		return err
+	}
+
+	if !rconSetDifficultyRegExp.MatchString(output) {
+		return unexpectedRCONOutput(command, output)
+	}
+
+	return nil
+}
+
+// Seed returns the seed of the world
+func (r *RCON) Seed() (int64, error) {
+	return r.SeedAs(RCONCaller{})
+}
+
+// SeedAs is the same as Seed, but runs the command as the caller
+func (r *RCON) SeedAs(caller RCONCaller) (int64, error) {
+	output, err := r.executeChecked(caller, "seed")
+
+	if err != nil {
+		return 0, err
+	}
+
+	match := rconSeedRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return 0, unexpectedRCONOutput("seed", output)
+	}
+
+	return strconv.ParseInt(match[1], 10, 64)
+}
+
+// SetWeather sets the weather to clear, rain or thunder, for the duration in seconds or a random duration if it is 0.
+// ErrInvalidArgument is returned for any other weather.
+func (r *RCON) SetWeather(weather string, seconds int) error {
+	return r.SetWeatherAs(RCONCaller{}, weather, seconds)
+}
+
+// SetWeatherAs is the same as SetWeather, but runs the command as the caller
+func (r *RCON) SetWeatherAs(caller RCONCaller, weather string, seconds int) error {
+	if !isRCONArgument(weather, "clear", "rain", "thunder") {
+		return ErrInvalidArgument
+	}
+
+	command := "weather " + weather
+
+	if seconds > 0 {
+		command += " " + strconv.Itoa(seconds)
+	}
+
+	return r.executeExpect(caller, command, "Set the weather to ", "Changing to ")
+}
+
+// WorldBorder returns the diameter of the world border in blocks
+func (r *RCON) WorldBorder() (float64, error) {
+	return r.WorldBorderAs(RCONCaller{})
+}
+
+// WorldBorderAs is the same as WorldBorder, but runs the command as the caller
+func (r *RCON) WorldBorderAs(caller RCONCaller) (float64, error) {
+	output, err := r.executeChecked(caller, "worldborder get")
+
+	if err != nil {
+		return 0, err
+	}
+
+	match := rconWorldBorderRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return 0, unexpectedRCONOutput("worldborder get", output)
+	}
+
+	return strconv.ParseFloat(match[1], 64)
+}
+
+// executeChecked runs the command as the caller and returns an RCONCommandError if the server responded with
+// an error
+func (r *RCON) executeChecked(caller RCONCaller, command string) (string, error) {
+	output, err := r.ExecuteAs(caller, command)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = CheckRCONOutput(command, output); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+// executeExpect runs the command and checks that the output starts with one of the prefixes
+func (r *RCON) executeExpect(caller RCONCaller, command string, prefixes ...string) error {
+	output, err := r.executeChecked(caller, command)
+
+	if err != nil {
+		return err
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(output, prefix) {
+			return nil
+		}
+	}
+
+	return unexpectedRCONOutput(command, output)
+}
+
+// executePlayerCommand runs the command on the player and checks that the output starts with one of the
+// prefixes. The name is checked before the command is sent, so it cannot be used to add to the command.
+func (r *RCON) executePlayerCommand(caller RCONCaller, command, player string, prefixes ...string) error {
+	if !rconPlayerNameRegExp.MatchString(player) {
+		return ErrInvalidPlayerName
+	}
+
+	return r.executeExpect(caller, command+" "+player, prefixes...)
+}
+
+// isRCONArgument returns whether the argument is one of the values, which keeps arguments from adding to a command
+func isRCONArgument(argument string, values ...string) bool {
+	for _, value := range values {
+		if argument == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitRCONNames splits a list of names separated by commas, with the last name possibly separated by "and"
+func splitRCONNames(value string) []string {
+	names := make([]string, 0)
+
+	for _, part := range strings.Split(value, ",") {
+		for _, name := range strings.Split(part, " and ") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+func unexpectedRCONOutput(command, output string) error {
+	return &RCONCommandError{
+		Command: command,
+		Output:  output,
+		Err:     ErrUnexpectedResponse,
+	}
+}
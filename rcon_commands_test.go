@@ -0,0 +1,244 @@
+package mcstatus_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+// testVanillaResponses are the responses of a vanilla server to the commands, as sent over RCON
+var testVanillaResponses = map[string]string{
+	"list":                "There are 2 of a max of 20 players online: Notch, jeb_",
+	"list uuids":          "There are 1 of a max of 20 players online: Notch (069a79f4-44e9-4726-a5be-fca90e38aaf5)",
+	"whitelist list":      "There are 3 whitelisted players: Notch, jeb_, Dinnerbone",
+	"whitelist add Notch": "Player is already whitelisted",
+	"whitelist add jeb_":  "Added jeb_ to the whitelist",
+	"whitelist remove x":  "That player does not exist",
+	"banlist":             "There are 2 ban(s):Notch was banned by Server: Banned by an operator.1.2.3.4 was banned by Rcon: Spam",
+	"op Notch":            "Made Notch a server operator",
+	"deop Notch":          "Nothing changed. The player is not an operator",
+	"time query daytime":  "The time is 6000",
+	"difficulty":          "The difficulty is Normal",
+	"seed":                "Seed: [-4172144997902289642]",
+	"weather rain 60":     "Set the weather to rain",
+	"worldborder get":     "The world border is currently 59999968 block(s) wide",
+	"seeds":               "Unknown or incomplete command, see below for error<--[HERE]",
+}
+
+func startTestVanillaRCON(t *testing.T) *mcstatus.RCON {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		return testVanillaResponses[command]
+	})
+
+	go server.Serve(l)
+
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestRCONCommands(t *testing.T) {
+	client := startTestVanillaRCON(t)
+
+	list, err := client.List(false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Online != 2 || list.Max != 20 || len(list.Players) != 2 || list.Players[1].Name != "jeb_" {
+		t.Fatalf("unexpected player list: %+v", list)
+	}
+
+	list, err = client.List(true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list.Players) != 1 || list.Players[0].Name != "Notch" || list.Players[0].UUID != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Fatalf("unexpected player list: %+v", list)
+	}
+
+	whitelist, err := client.WhitelistList()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(whitelist) != 3 || whitelist[2] != "Dinnerbone" {
+		t.Fatalf("unexpected whitelist: %v", whitelist)
+	}
+
+	if err = client.WhitelistAdd("jeb_"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.WhitelistAdd("Notch"); !errors.Is(err, mcstatus.ErrNothingChanged) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = client.WhitelistRemove("x")
+
+	var commandErr *mcstatus.RCONCommandError
+
+	if !errors.As(err, &commandErr) || commandErr.Err != mcstatus.ErrPlayerNotFound || commandErr.Command != "whitelist remove x" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	bans, err := client.Banlist()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bans) != 2 || bans[0].Target != "Notch" || bans[0].Reason != "Banned by an operator." || bans[1].Target != "1.2.3.4" || bans[1].Source != "Rcon" || bans[1].Reason != "Spam" {
+		t.Fatalf("unexpected bans: %+v", bans)
+	}
+
+	if err = client.Op("Notch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Deop("Notch"); !errors.Is(err, mcstatus.ErrNothingChanged) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if daytime, err := client.QueryTime("daytime"); err != nil || daytime != 6000 {
+		t.Fatalf("unexpected time: %d, %v", daytime, err)
+	}
+
+	if difficulty, err := client.Difficulty(); err != nil || difficulty != "Normal" {
+		t.Fatalf("unexpected difficulty: %s, %v", difficulty, err)
+	}
+
+	if seed, err := client.Seed(); err != nil || seed != -4172144997902289642 {
+		t.Fatalf("unexpected seed: %d, %v", seed, err)
+	}
+
+	if err = client.SetWeather("rain", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	if size, err := client.WorldBorder(); err != nil || size != 59999968 {
+		t.Fatalf("unexpected world border: %f, %v", size, err)
+	}
+
+	// Responses that cannot be parsed are reported as unexpected
+	if _, err = client.QueryTime("gametime"); !errors.Is(err, mcstatus.ErrUnexpectedResponse) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckRCONOutput(t *testing.T) {
+	if err := mcstatus.CheckRCONOutput("seeds", testVanillaResponses["seeds"]); !errors.Is(err, mcstatus.ErrUnknownCommand) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mcstatus.CheckRCONOutput("seed", testVanillaResponses["seed"]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRCONCommandsAs(t *testing.T) {
+	commands := make(chan string, 16)
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		commands <- command
+
+		return testVanillaResponses[command]
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	policy := mcstatus.NewRCONPolicy()
+
+	if err = policy.Allow("moderator", "whitelist <action> <player>"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := mcstatus.NewRCON()
+
+	if err = client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), mcstatus.RCONOptions{Timeout: time.Second * 5, Policy: policy}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err = client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	moderator := mcstatus.RCONCaller{Identity: "alice", Role: "moderator"}
+
+	if err = client.WhitelistAddAs(moderator, "jeb_"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.OpAs(moderator, "Notch"); !errors.Is(err, mcstatus.ErrCommandDenied) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Names that could add to the command are rejected before it is sent
+	for _, name := range []string{"x\nstop", "Notch stop", "", "ThisNameIsTooLong"} {
+		if err = client.WhitelistAddAs(moderator, name); err != mcstatus.ErrInvalidPlayerName {
+			t.Fatalf("unexpected error for %q: %v", name, err)
+		}
+	}
+
+	// Arguments that are not one of the values of the command are rejected before it is sent
+	if _, err = client.QueryTimeAs(moderator, "day run stop"); err != mcstatus.ErrInvalidArgument {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = client.SetDifficultyAs(moderator, "hard\nstop"); err != mcstatus.ErrInvalidArgument {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = client.SetWeatherAs(moderator, "rain 60 stop", 0); err != mcstatus.ErrInvalidArgument {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if command := <-commands; command != "whitelist add jeb_" {
+		t.Fatalf("unexpected command: %q", command)
+	}
+
+	select {
+	case command := <-commands:
+		t.Fatalf("unexpected command: %q", command)
+	default:
+	}
+}
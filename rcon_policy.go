@@ -0,0 +1,342 @@
+package mcstatus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RCONPolicy decides which commands a caller may run, based on the role of the caller. Each role has a list
+// of allowed and denied patterns, and the patterns of the empty role apply to every role. A command is denied
+// if it matches any denied pattern, otherwise it is only allowed if it matches an allowed pattern, so a policy
+// without any allowed patterns denies every command, and so does a role that has not been added to the policy.
+// The RCON client checks the policy before sending a command, so a denied command never reaches the server.
+//
+// The command run by "execute ... run" is checked as well, so "execute as @a run stop" is denied by the
+// same patterns as "stop", and is only allowed if both the execute command and the command it runs are.
+//
+// Patterns are matched against the whole command, ignoring case, a leading slash and any namespace of the
+// command such as "minecraft:stop". Three kinds of pattern are supported:
+//
+//	kick *              a glob, where * matches any text and ? matches a single character
+//	kick <player>       a usage template, where <arg> is a single argument, [<arg>] is an optional argument
+//	                    and <arg...> is the rest of the command
+//	/kick \w{3,16}/     a regular expression
+type RCONPolicy struct {
+	roles map[string]*rconPolicyRole
+	lock  sync.RWMutex
+}
+
+type rconPolicyRole struct {
+	allow []rconPolicyRule
+	deny  []rconPolicyRule
+}
+
+type rconPolicyRule struct {
+	pattern string
+	regexp  *regexp.Regexp
+}
+
+// RCONPolicyError is returned when a policy denies a command, the rule is the pattern that denied the command,
+// or empty if the command was denied because it did not match any allowed pattern
+type RCONPolicyError struct {
+	Caller  RCONCaller
+	Command string
+	Rule    string
+}
+
+func (e *RCONPolicyError) Error() string {
+	if len(e.Rule) < 1 {
+		return fmt.Sprintf("command %q is not allowed for role %q", e.Command, e.Caller.Role)
+	}
+
+	return fmt.Sprintf("command %q is denied for role %q by rule %q", e.Command, e.Caller.Role, e.Rule)
+}
+
+func (e *RCONPolicyError) Unwrap() error {
+	return ErrCommandDenied
+}
+
+// NewRCONPolicy creates a new policy that denies every command until patterns are allowed
+func NewRCONPolicy() *RCONPolicy {
+	return &RCONPolicy{
+		roles: make(map[string]*rconPolicyRole),
+	}
+}
+
+// Allow adds allowed patterns to the role, or to every role if the role is empty
+func (p *RCONPolicy) Allow(role string, patterns ...string) error {
+	rules, err := compileRCONPolicyRules(patterns)
+
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	r := p.role(role)
+	r.allow = append(r.allow, rules...)
+
+	return nil
+}
+
+// Deny adds denied patterns to the role, or to every role if the role is empty
+func (p *RCONPolicy) Deny(role string, patterns ...string) error {
+	rules, err := compileRCONPolicyRules(patterns)
+
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	r := p.role(role)
+	r.deny = append(r.deny, rules...)
+
+	return nil
+}
+
+// Check returns an RCONPolicyError if the caller is not allowed to run the command, or nil if they are
+func (p *RCONPolicy) Check(caller RCONCaller, command string) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	roles := []*rconPolicyRole{p.roles[""]}
+
+	if len(caller.Role) > 0 {
+		r, ok := p.roles[caller.Role]
+
+		if !ok {
+			return &RCONPolicyError{
+				Caller:  caller,
+				Command: command,
+			}
+		}
+
+		roles = append(roles, r)
+	}
+
+	normalized := normalizeRCONCommand(command)
+
+	// Any command that could be run by execute is checked against the denied patterns, as an argument of
+	// execute may itself be the word "run"
+	for _, c := range append([]string{normalized}, rconExecuteRunCommands(normalized)...) {
+		for _, r := range roles {
+			if r == nil {
+				continue
+			}
+
+			for _, rule := range r.deny {
+				if rule.regexp.MatchString(c) {
+					return &RCONPolicyError{
+						Caller:  caller,
+						Command: command,
+						Rule:    rule.pattern,
+					}
+				}
+			}
+		}
+	}
+
+	// The command and each command it runs must be allowed
+	for c := normalized; ; {
+		if !rconPolicyAllows(roles, c) {
+			return &RCONPolicyError{
+				Caller:  caller,
+				Command: command,
+			}
+		}
+
+		if c = rconExecuteRunCommand(c); len(c) < 1 {
+			return nil
+		}
+	}
+}
+
+// role returns the rules of the role, creating them if they do not exist, the policy must be locked
+func (p *RCONPolicy) role(name string) *rconPolicyRole {
+	r, ok := p.roles[name]
+
+	if !ok {
+		r = &rconPolicyRole{}
+
+		p.roles[name] = r
+	}
+
+	return r
+}
+
+// rconPolicyAllows returns whether the normalized command matches an allowed pattern of any of the roles
+func rconPolicyAllows(roles []*rconPolicyRole, command string) bool {
+	for _, r := range roles {
+		if r == nil {
+			continue
+		}
+
+		for _, rule := range r.allow {
+			if rule.regexp.MatchString(command) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rconExecuteRunCommand returns the normalized command run by an execute command, which is everything after
+// its first "run" argument, or an empty string if the command is not an execute command that runs a command
+func rconExecuteRunCommand(command string) string {
+	if commands := rconExecuteRunCommands(command); len(commands) > 0 {
+		return commands[0]
+	}
+
+	return ""
+}
+
+// rconExecuteRunCommands returns every normalized command that could be run by an execute command, which is
+// everything after any of its "run" arguments
+func rconExecuteRunCommands(command string) []string {
+	fields := strings.Fields(command)
+	commands := make([]string, 0)
+
+	if len(fields) < 1 || !strings.EqualFold(fields[0], "execute") {
+		return commands
+	}
+
+	for i := 1; i < len(fields)-1; i++ {
+		if strings.EqualFold(fields[i], "run") {
+			commands = append(commands, normalizeRCONCommand(strings.Join(fields[i+1:], " ")))
+		}
+	}
+
+	return commands
+}
+
+func compileRCONPolicyRules(patterns []string) ([]rconPolicyRule, error) {
+	rules := make([]rconPolicyRule, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		expression := ""
+
+		switch {
+		case isRCONRegExpPattern(pattern):
+			expression = "(?i)^(?:" + pattern[1:len(pattern)-1] + ")$"
+		case strings.Contains(pattern, "<"):
+			{
+				var err error
+
+				if expression, err = rconTemplateExpression(pattern); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			expression = rconGlobExpression(pattern)
+		}
+
+		re, err := regexp.Compile(expression)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid RCON policy pattern %q: %w", pattern, err)
+		}
+
+		rules = append(rules, rconPolicyRule{
+			pattern: pattern,
+			regexp:  re,
+		})
+	}
+
+	return rules, nil
+}
+
+func isRCONRegExpPattern(pattern string) bool {
+	return len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+// rconGlobExpression converts a glob pattern to a case-insensitive regular expression
+func rconGlobExpression(pattern string) string {
+	result := "(?i)^"
+
+	for _, c := range normalizeRCONCommand(pattern) {
+		switch c {
+		case '*':
+			result += ".*"
+		case '?':
+			result += "."
+		default:
+			result += regexp.QuoteMeta(string(c))
+		}
+	}
+
+	return result + "$"
+}
+
+// rconTemplateExpression converts a usage template such as "kick <player> [<reason...>]" to a case-insensitive
+// regular expression
+func rconTemplateExpression(pattern string) (string, error) {
+	result := "(?i)^"
+
+	for i, token := range strings.Fields(normalizeRCONCommand(pattern)) {
+		optional := strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]")
+
+		if optional {
+			token = token[1 : len(token)-1]
+		}
+
+		part := ""
+
+		switch {
+		case strings.HasPrefix(token, "<") && strings.HasSuffix(token, "...>"):
+			part = " .+"
+		case strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">"):
+			part = ` \S+`
+		case strings.ContainsAny(token, "<>[]"):
+			return "", fmt.Errorf("invalid RCON policy pattern %q: malformed argument %q", pattern, token)
+		default:
+			{
+				if optional {
+					return "", fmt.Errorf("invalid RCON policy pattern %q: optional literal %q", pattern, token)
+				}
+
+				part = " " + regexp.QuoteMeta(token)
+			}
+		}
+
+		if optional {
+			part = "(?:" + part + ")?"
+		}
+
+		// The first word has no space before it
+		if i == 0 {
+			if optional {
+				return "", fmt.Errorf("invalid RCON policy pattern %q: must start with the command name", pattern)
+			}
+
+			part = part[1:]
+		}
+
+		result += part
+	}
+
+	return result + "$", nil
+}
+
+// normalizeRCONCommand removes a leading slash, the namespace of the command and any repeated whitespace, so
+// that "/minecraft:stop" and "stop" are matched by the same patterns
+func normalizeRCONCommand(command string) string {
+	fields := strings.Fields(command)
+
+	if len(fields) < 1 {
+		return ""
+	}
+
+	fields[0] = strings.TrimPrefix(fields[0], "/")
+
+	if i := strings.LastIndex(fields[0], ":"); i >= 0 {
+		fields[0] = fields[0][i+1:]
+	}
+
+	return strings.Join(fields, " ")
+}
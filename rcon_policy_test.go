@@ -0,0 +1,195 @@
+package mcstatus_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestRCONPolicy(t *testing.T) {
+	policy := mcstatus.NewRCONPolicy()
+
+	if err := policy.Deny("", "stop", "op *", "/deop \\w+/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := policy.Allow("moderator", "kick <player> [<reason...>]", "list", "say *", "execute as <target> run <command...>"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := policy.Allow("admin", "*"); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := mcstatus.RCONCaller{Identity: "alice", Role: "admin"}
+	moderator := mcstatus.RCONCaller{Identity: "bob", Role: "moderator"}
+	helper := mcstatus.RCONCaller{Identity: "carol", Role: "helper"}
+
+	tests := []struct {
+		caller  mcstatus.RCONCaller
+		command string
+		rule    string
+		allowed bool
+	}{
+		{admin, "whitelist add Notch", "", true},
+		{admin, "stop", "stop", false},
+		{admin, "/minecraft:STOP", "stop", false},
+		{admin, "op  Notch", "op *", false},
+		{admin, "deop Notch", "/deop \\w+/", false},
+		{admin, "/minecraft:deop Notch", "/deop \\w+/", false},
+		{admin, "execute run stop", "stop", false},
+		{admin, "execute as @s run op x", "op *", false},
+		{admin, "execute as @a run execute at @s run minecraft:stop", "stop", false},
+		{admin, "execute as run run stop", "stop", false},
+		{moderator, "kick Notch", "", true},
+		{moderator, "kick Notch Spamming the chat", "", true},
+		{moderator, "kick", "", false},
+		{moderator, "kick @a", "", true},
+		{moderator, "ban Notch", "", false},
+		{moderator, "stop", "stop", false},
+		{moderator, "List", "", true},
+		{moderator, "execute as @a run say hi", "", true},
+		{moderator, "execute as @a run ban Notch", "", false},
+		{moderator, "execute at @a run say hi", "", false},
+		{helper, "list", "", false},
+		{mcstatus.RCONCaller{}, "list", "", false},
+	}
+
+	for _, test := range tests {
+		err := policy.Check(test.caller, test.command)
+
+		if test.allowed {
+			if err != nil {
+				t.Fatalf("expected %q to be allowed for %s, got %v", test.command, test.caller.Role, err)
+			}
+
+			continue
+		}
+
+		var policyErr *mcstatus.RCONPolicyError
+
+		if !errors.As(err, &policyErr) || !errors.Is(err, mcstatus.ErrCommandDenied) {
+			t.Fatalf("expected %q to be denied for %s, got %v", test.command, test.caller.Role, err)
+		}
+
+		if policyErr.Rule != test.rule || policyErr.Caller != test.caller {
+			t.Fatalf("unexpected error for %q: %+v", test.command, policyErr)
+		}
+	}
+
+	// A policy without any allowed patterns denies every command
+	if err := mcstatus.NewRCONPolicy().Check(admin, "list"); !errors.Is(err, mcstatus.ErrCommandDenied) {
+		t.Fatalf("expected an empty policy to deny commands, got %v", err)
+	}
+
+	for _, pattern := range []string{"/[/", "[<player>] kick", "kick <player"} {
+		if err := policy.Allow("moderator", pattern); err == nil {
+			t.Fatalf("expected an error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestRCONPolicyRegExp(t *testing.T) {
+	policy := mcstatus.NewRCONPolicy()
+
+	if err := policy.Allow("", "*"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := policy.Deny("", "/stop/", "/save-(off|all)/"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"stop", false},
+		{"STOP", false},
+		{"/minecraft:Stop", false},
+		{"nostop", true},
+		{"stopwatch", true},
+		{"Save-Off", false},
+		{"save-on", true},
+	}
+
+	for _, test := range tests {
+		err := policy.Check(mcstatus.RCONCaller{}, test.command)
+
+		if test.allowed && err != nil {
+			t.Fatalf("expected %q to be allowed, got %v", test.command, err)
+		}
+
+		if !test.allowed && !errors.Is(err, mcstatus.ErrCommandDenied) {
+			t.Fatalf("expected %q to be denied, got %v", test.command, err)
+		}
+	}
+}
+
+func TestRCONPolicyEnforced(t *testing.T) {
+	commands := make(chan string, 16)
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		commands <- command
+
+		return ""
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	policy := mcstatus.NewRCONPolicy()
+
+	if err = policy.Allow("", "*"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = policy.Deny("", "stop"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := mcstatus.NewRCON()
+
+	if err = client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), mcstatus.RCONOptions{Timeout: time.Second * 5, Policy: policy}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err = client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Run("stop"); !errors.Is(err, mcstatus.ErrCommandDenied) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err = client.ExecuteAs(mcstatus.RCONCaller{Identity: "alice"}, "stop"); !errors.Is(err, mcstatus.ErrCommandDenied) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err = client.Execute("list"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the allowed command reached the server
+	if command := <-commands; command != "list" {
+		t.Fatalf("unexpected command: %q", command)
+	}
+
+	select {
+	case command := <-commands:
+		t.Fatalf("unexpected command: %q", command)
+	default:
+	}
+}
@@ -0,0 +1,417 @@
+package mcstatus
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	defaultRCONPoolOptions = RCONPoolOptions{
+		RCONOptions: RCONOptions{
+			Timeout: time.Second * 5,
+		},
+		Connections:         1,
+		CommandTimeout:      time.Second * 10,
+		HealthCheckInterval: time.Second * 30,
+		MinBackoff:          time.Second,
+		MaxBackoff:          time.Second * 30,
+	}
+)
+
+type RCONPoolOptions struct {
+	RCONOptions         RCONOptions
+	Connections         int
+	CommandTimeout      time.Duration
+	HealthCheckInterval time.Duration
+	MinBackoff          time.Duration
+	MaxBackoff          time.Duration
+}
+
+// RCONPool keeps authenticated RCON connections to a set of servers and is safe for concurrent use. Commands
+// are spread over the connections of a server, and connections that fail are replaced when they are next
+// used, waiting longer between each failed attempt so that a server that is restarting is not flooded with
+// connections. Idle connections are checked in the background, so a dropped connection is noticed before a
+// command is sent over it.
+type RCONPool struct {
+	options RCONPoolOptions
+	servers map[string]*rconPoolServer
+	closed  bool
+	lock    sync.RWMutex
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type rconPoolServer struct {
+	host     string
+	port     uint16
+	password string
+	slots    []*rconPoolSlot
+	next     uint32
+}
+
+// rconPoolSlot is a single connection of a server, which is only reconnected once the backoff has passed
+type rconPoolSlot struct {
+	client   *RCON
+	failures int
+	retryAt  time.Time
+	lastErr  error
+	lock     sync.Mutex
+}
+
+// NewRCONPool creates a new empty pool and starts checking the health of its connections
+func NewRCONPool(options ...RCONPoolOptions) *RCONPool {
+	p := &RCONPool{
+		options: parseRCONPoolOptions(options...),
+		servers: make(map[string]*rconPoolServer),
+		done:    make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+
+	go p.healthCheck()
+
+	return p
+}
+
+// Add adds a server to the pool under the name, replacing any server with the same name. Connections are
+// opened when the server is first used.
+func (p *RCONPool) Add(name, host string, port uint16, password string) error {
+	server := &rconPoolServer{
+		host:     host,
+		port:     port,
+		password: password,
+		slots:    make([]*rconPoolSlot, p.options.Connections),
+	}
+
+	for i := range server.slots {
+		server.slots[i] = &rconPoolSlot{}
+	}
+
+	p.lock.Lock()
+
+	if p.closed {
+		p.lock.Unlock()
+
+		return ErrServerClosed
+	}
+
+	previous := p.servers[name]
+	p.servers[name] = server
+
+	p.lock.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+
+	return nil
+}
+
+// Remove removes the server from the pool and closes its connections
+func (p *RCONPool) Remove(name string) error {
+	p.lock.Lock()
+
+	server, ok := p.servers[name]
+	delete(p.servers, name)
+
+	p.lock.Unlock()
+
+	if !ok {
+		return ErrUnknownServer
+	}
+
+	server.close()
+
+	return nil
+}
+
+// Servers returns the names of every server in the pool, in alphabetical order
+func (p *RCONPool) Servers() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	names := make([]string, 0, len(p.servers))
+
+	for name := range p.servers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Execute runs the command on the server with the name and returns its output, waiting for the command
+// timeout of the pool
+func (p *RCONPool) Execute(name, command string) (string, error) {
+	return p.ExecuteTimeout(name, command, p.options.CommandTimeout)
+}
+
+// ExecuteTimeout runs the command on the server with the name and returns its output, waiting until the timeout
+func (p *RCONPool) ExecuteTimeout(name, command string, timeout time.Duration) (string, error) {
+	return p.executeAs(RCONCaller{}, name, command, timeout)
+}
+
+// ExecuteAs runs the command on the server with the name and records the caller in the audit log, which is
+// set with the auditor of the RCON options of the pool
+func (p *RCONPool) ExecuteAs(caller RCONCaller, name, command string) (string, error) {
+	return p.executeAs(caller, name, command, p.options.CommandTimeout)
+}
+
+func (p *RCONPool) executeAs(caller RCONCaller, name, command string, timeout time.Duration) (string, error) {
+	p.lock.RLock()
+
+	server, ok := p.servers[name]
+	closed := p.closed
+
+	p.lock.RUnlock()
+
+	if closed {
+		return "", ErrServerClosed
+	}
+
+	if !ok {
+		return "", ErrUnknownServer
+	}
+
+	slot := server.slots[int(atomic.AddUint32(&server.next, 1)-1)%len(server.slots)]
+
+	client, err := slot.acquire(server, p.options)
+
+	if err != nil {
+		return "", err
+	}
+
+	output, err := client.executeAudited(caller, command, timeout)
+
+	// Only a failure of the connection means it has to be replaced. A timed out command does not mean the
+	// connection is broken, the health check decides that instead, commands that were denied by the policy
+	// or were too large to send never reached the server, and a failure to audit a command is not a failure
+	// of the connection.
+	var auditErr *RCONAuditError
+
+	if err != nil && err != ErrTimeout && err != ErrPacketTooLarge && !errors.Is(err, ErrCommandDenied) && !errors.As(err, &auditErr) {
+		slot.release(client)
+	}
+
+	return output, err
+}
+
+// ExecuteFormatted runs the command on the server with the name and parses the formatting codes in its output
+func (p *RCONPool) ExecuteFormatted(name, command string) (*MOTD, error) {
+	output, err := p.Execute(name, command)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMOTD(output)
+}
+
+// Close closes every connection in the pool and stops the health checks
+func (p *RCONPool) Close() error {
+	p.lock.Lock()
+
+	if p.closed {
+		p.lock.Unlock()
+
+		return ErrServerClosed
+	}
+
+	p.closed = true
+
+	servers := p.servers
+	p.servers = make(map[string]*rconPoolServer)
+
+	p.lock.Unlock()
+
+	close(p.done)
+
+	p.wg.Wait()
+
+	for _, server := range servers {
+		server.close()
+	}
+
+	return nil
+}
+
+func (p *RCONPool) healthCheck() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.options.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.lock.RLock()
+
+		servers := make([]*rconPoolServer, 0, len(p.servers))
+
+		for _, server := range p.servers {
+			servers = append(servers, server)
+		}
+
+		p.lock.RUnlock()
+
+		wg := &sync.WaitGroup{}
+
+		for _, server := range servers {
+			for _, slot := range server.slots {
+				wg.Add(1)
+
+				go (func(server *rconPoolServer, slot *rconPoolSlot) {
+					defer wg.Done()
+
+					slot.check(server, p.options)
+				})(server, slot)
+			}
+		}
+
+		wg.Wait()
+	}
+}
+
+// acquire returns the connection of the slot, connecting to the server if the slot has no working connection
+// and the backoff after the last failure has passed
+func (s *rconPoolSlot) acquire(server *rconPoolServer, options RCONPoolOptions) (*RCON, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.client != nil {
+		if s.client.healthy() {
+			return s.client, nil
+		}
+
+		s.client.Close()
+		s.client = nil
+	}
+
+	if s.lastErr != nil && time.Now().Before(s.retryAt) {
+		return nil, s.lastErr
+	}
+
+	client := NewRCON()
+
+	err := client.Dial(server.host, server.port, options.RCONOptions)
+
+	if err == nil {
+		if err = client.Login(server.password); err != nil {
+			client.Close()
+		}
+	}
+
+	if err != nil {
+		s.fail(err, options)
+
+		return nil, err
+	}
+
+	s.client = client
+	s.failures = 0
+	s.lastErr = nil
+
+	return client, nil
+}
+
+// release closes the connection after a command failed on it, so that the next command reconnects. The
+// backoff only applies to failed attempts to connect, a connection that was dropped is replaced straight away.
+func (s *rconPoolSlot) release(client *RCON) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.client != client {
+		return
+	}
+
+	s.client.Close()
+	s.client = nil
+}
+
+// check pings the connection of the slot and replaces it if it does not respond, slots that lost their
+// connection are reconnected once their backoff has passed
+func (s *rconPoolSlot) check(server *rconPoolServer, options RCONPoolOptions) {
+	s.lock.Lock()
+	client, lastErr := s.client, s.lastErr
+	s.lock.Unlock()
+
+	if client != nil {
+		if err := client.ping(options.RCONOptions.Timeout); err == nil {
+			return
+		}
+
+		s.release(client)
+	} else if lastErr == nil {
+		// The slot has not been used yet
+		return
+	}
+
+	s.acquire(server, options)
+}
+
+// fail records a failed connection and schedules the next attempt, the slot must be locked
+func (s *rconPoolSlot) fail(err error, options RCONPoolOptions) {
+	s.failures++
+	s.lastErr = err
+
+	backoff := retryBackoff(options.MinBackoff, s.failures)
+
+	if backoff > options.MaxBackoff {
+		backoff = options.MaxBackoff
+	}
+
+	s.retryAt = time.Now().Add(backoff)
+}
+
+func (s *rconPoolServer) close() {
+	for _, slot := range s.slots {
+		slot.lock.Lock()
+
+		if slot.client != nil {
+			slot.client.Close()
+			slot.client = nil
+		}
+
+		slot.lock.Unlock()
+	}
+}
+
+func parseRCONPoolOptions(opts ...RCONPoolOptions) RCONPoolOptions {
+	if len(opts) < 1 {
+		return defaultRCONPoolOptions
+	}
+
+	options := opts[0]
+
+	options.RCONOptions = parseRCONOptions(options.RCONOptions)
+
+	if options.Connections < 1 {
+		options.Connections = defaultRCONPoolOptions.Connections
+	}
+
+	if options.CommandTimeout <= 0 {
+		options.CommandTimeout = defaultRCONPoolOptions.CommandTimeout
+	}
+
+	if options.HealthCheckInterval <= 0 {
+		options.HealthCheckInterval = defaultRCONPoolOptions.HealthCheckInterval
+	}
+
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = defaultRCONPoolOptions.MinBackoff
+	}
+
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaultRCONPoolOptions.MaxBackoff
+	}
+
+	return options
+}
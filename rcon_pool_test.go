@@ -0,0 +1,271 @@
+package mcstatus_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+// testRCONServer is an RCON server that can be stopped and started again on the same port
+type testRCONServer struct {
+	address string
+	port    uint16
+	server  *mcstatus.RCONServer
+}
+
+func (s *testRCONServer) start(t *testing.T) {
+	l, err := net.Listen("tcp4", s.address)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.address = l.Addr().String()
+	s.port = uint16(l.Addr().(*net.TCPAddr).Port)
+	s.server = mcstatus.NewRCONServer("password", testRCONHandler)
+
+	go s.server.Serve(l)
+}
+
+func (s *testRCONServer) stop() {
+	s.server.Close()
+}
+
+func TestRCONPool(t *testing.T) {
+	server := &testRCONServer{address: "127.0.0.1:0"}
+	server.start(t)
+
+	defer server.stop()
+
+	pool := mcstatus.NewRCONPool(mcstatus.RCONPoolOptions{
+		RCONOptions:    mcstatus.RCONOptions{Timeout: time.Second},
+		Connections:    1,
+		CommandTimeout: time.Second,
+		MinBackoff:     time.Millisecond * 100,
+		MaxBackoff:     time.Millisecond * 200,
+	})
+
+	defer pool.Close()
+
+	if _, err := pool.Execute("lobby", "list"); err != mcstatus.ErrUnknownServer {
+		t.Fatalf("expected ErrUnknownServer, got %v", err)
+	}
+
+	if err := pool.Add("lobby", "127.0.0.1", server.port, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go (func() {
+			defer wg.Done()
+
+			if output, err := pool.Execute("lobby", "list"); err != nil || output != "list" {
+				t.Errorf("unexpected result: %q, %v", output, err)
+			}
+		})()
+	}
+
+	wg.Wait()
+
+	// The server restarts, commands fail until it is back and the backoff has passed
+	server.stop()
+
+	if _, err := pool.Execute("lobby", "list"); err == nil {
+		t.Fatal("expected an error while the server is down")
+	}
+
+	server.start(t)
+
+	deadline := time.Now().Add(time.Second * 3)
+
+	for {
+		output, err := pool.Execute("lobby", "list")
+
+		if err == nil && output == "list" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("pool did not reconnect: %v", err)
+		}
+
+		time.Sleep(time.Millisecond * 20)
+	}
+}
+
+func TestRCONPoolHealthCheck(t *testing.T) {
+	server := &testRCONServer{address: "127.0.0.1:0"}
+	server.start(t)
+
+	defer server.stop()
+
+	pool := mcstatus.NewRCONPool(mcstatus.RCONPoolOptions{
+		RCONOptions:         mcstatus.RCONOptions{Timeout: time.Second},
+		Connections:         2,
+		CommandTimeout:      time.Second,
+		HealthCheckInterval: time.Millisecond * 50,
+		MinBackoff:          time.Millisecond * 50,
+		MaxBackoff:          time.Millisecond * 100,
+	})
+
+	defer pool.Close()
+
+	if err := pool.Add("survival", "127.0.0.1", server.port, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Execute("survival", "list"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Restarting the server drops every connection
+	server.stop()
+	server.start(t)
+
+	// The health check notices the dropped connections and reconnects them before they are used
+	time.Sleep(time.Millisecond * 300)
+
+	for i := 0; i < 2; i++ {
+		if output, err := pool.Execute("survival", "list"); err != nil || output != "list" {
+			t.Fatalf("unexpected result: %q, %v", output, err)
+		}
+	}
+}
+
+// countingListener counts the connections accepted by the listener
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+
+	return conn, err
+}
+
+func TestRCONPoolKeepsConnection(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := &countingListener{Listener: l}
+	server := mcstatus.NewRCONServer("password", testRCONHandler)
+
+	go server.Serve(listener)
+
+	defer server.Close()
+
+	policy := mcstatus.NewRCONPolicy()
+
+	if err = policy.Allow("", "*"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = policy.Deny("", "stop"); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := mcstatus.NewRCONPool(mcstatus.RCONPoolOptions{
+		RCONOptions:    mcstatus.RCONOptions{Timeout: time.Second, Policy: policy},
+		Connections:    1,
+		CommandTimeout: time.Second,
+	})
+
+	defer pool.Close()
+
+	if err = pool.Add("lobby", "127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if output, err := pool.Execute("lobby", "list"); err != nil || output != "list" {
+			t.Fatalf("unexpected result: %q, %v", output, err)
+		}
+
+		if _, err = pool.Execute("lobby", "stop"); !errors.Is(err, mcstatus.ErrCommandDenied) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err = pool.Execute("lobby", strings.Repeat("x", 1<<16)); err != mcstatus.ErrPacketTooLarge {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Neither error replaced the connection of the pool
+	if accepted := atomic.LoadInt32(&listener.accepted); accepted != 1 {
+		t.Fatalf("expected a single connection, got %d", accepted)
+	}
+}
+
+func TestRCONPoolDefaultOptions(t *testing.T) {
+	port := startTestRCON(t)
+
+	// Only the number of connections is set, every other option uses its default
+	pool := mcstatus.NewRCONPool(mcstatus.RCONPoolOptions{Connections: 1})
+
+	defer pool.Close()
+
+	if err := pool.Add("lobby", "127.0.0.1", port, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := pool.Execute("lobby", "list"); err != nil || output != "list" {
+		t.Fatalf("unexpected result: %q, %v", output, err)
+	}
+
+	// A server that drops every connection is not dialed again until the backoff has passed
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	listener := &countingListener{Listener: l}
+
+	go (func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			conn.Close()
+		}
+	})()
+
+	if err = pool.Add("hub", "127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = pool.Execute("hub", "list"); err == nil {
+			t.Fatal("expected an error from a server that drops connections")
+		}
+	}
+
+	if accepted := atomic.LoadInt32(&listener.accepted); accepted != 1 {
+		t.Fatalf("expected a single connection before the backoff passed, got %d", accepted)
+	}
+}
@@ -0,0 +1,560 @@
+package mcstatus
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	defaultRCONSchedulerOptions = RCONSchedulerOptions{
+		Location: time.Local,
+	}
+)
+
+// RCONMissedRun is what a scheduler does with a run of a job that was missed, because the scheduler was not
+// running or the system was suspended at the time
+type RCONMissedRun int
+
+const (
+	// RCONMissedRunSkip skips missed runs, the job next runs at its next scheduled time
+	RCONMissedRunSkip RCONMissedRun = iota
+	// RCONMissedRunOnce runs the job once as soon as possible, no matter how many runs were missed
+	RCONMissedRunOnce
+)
+
+// RCONCondition decides whether a job runs on the server with the name in the pool, it is checked before
+// every run
+type RCONCondition func(pool *RCONPool, server string) (bool, error)
+
+// RCONJob is a list of commands that a scheduler runs on a cron schedule, see ParseCronSchedule for the
+// format of the schedule. The job runs on every server in the pool if no servers are given.
+type RCONJob struct {
+	Name      string
+	Schedule  string
+	Servers   []string
+	Commands  []string
+	Condition RCONCondition
+	Caller    RCONCaller
+	MissedRun RCONMissedRun
+}
+
+// RCONJobResult is the result of a run of a job on a single server. The commands are run in order, stopping
+// at the first one that fails, so there is an output for every command that succeeded.
+type RCONJobResult struct {
+	Job       string
+	Server    string
+	Scheduled time.Time
+	Started   time.Time
+	Skipped   bool
+	Outputs   []string
+	Err       error
+}
+
+type RCONSchedulerOptions struct {
+	StateFile string
+	Location  *time.Location
+	OnResult  func(result RCONJobResult)
+}
+
+// RCONScheduler runs jobs on the servers of an RCON pool on cron schedules. Runs start at exactly their
+// scheduled time, and the next run is calculated from the scheduled time rather than from when the run
+// finished, so runs do not drift. If a run of a job is still going when the next is due, the next run is
+// skipped. The time of the last run of each job is kept in the state file, if one is set, so that runs missed
+// while the scheduler was not running are handled by the missed run policy of the job after a restart.
+type RCONScheduler struct {
+	pool    *RCONPool
+	options RCONSchedulerOptions
+	jobs    map[string]*rconScheduledJob
+	lastRun map[string]time.Time
+	started bool
+	closed  bool
+	lock    sync.Mutex
+	// saveLock is held while writing the state file, so that an older state never replaces a newer one
+	saveLock sync.Mutex
+	wake     chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+type rconScheduledJob struct {
+	job      RCONJob
+	schedule *CronSchedule
+	next     time.Time
+	running  bool
+}
+
+// rconSchedulerState is the contents of the state file of a scheduler
+type rconSchedulerState struct {
+	LastRun map[string]time.Time `json:"last_run"`
+}
+
+// NewRCONScheduler creates a new scheduler that runs jobs on the servers of the pool
+func NewRCONScheduler(pool *RCONPool, options ...RCONSchedulerOptions) *RCONScheduler {
+	opts := parseRCONSchedulerOptions(options...)
+
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	return &RCONScheduler{
+		pool:    pool,
+		options: opts,
+		jobs:    make(map[string]*rconScheduledJob),
+		lastRun: make(map[string]time.Time),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// Add adds the job to the scheduler, the name of the job must be unique
+func (s *RCONScheduler) Add(job RCONJob) error {
+	schedule, err := ParseCronSchedule(job.Schedule)
+
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrServerClosed
+	}
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return ErrJobExists
+	}
+
+	scheduled := &rconScheduledJob{
+		job:      job,
+		schedule: schedule,
+	}
+
+	s.jobs[job.Name] = scheduled
+
+	if s.started {
+		s.scheduleFirst(scheduled, time.Now())
+		s.notify()
+	}
+
+	return nil
+}
+
+// Remove removes the job from the scheduler, a run of the job that has already started is not stopped
+func (s *RCONScheduler) Remove(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.jobs[name]; !ok {
+		return ErrUnknownJob
+	}
+
+	delete(s.jobs, name)
+
+	s.notify()
+
+	return nil
+}
+
+// Jobs returns the names of every job in the scheduler, in alphabetical order
+func (s *RCONScheduler) Jobs() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := make([]string, 0, len(s.jobs))
+
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Next returns the time of the next run of the job, or the zero time if the scheduler has not been started
+func (s *RCONScheduler) Next(name string) (time.Time, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job, ok := s.jobs[name]
+
+	if !ok {
+		return time.Time{}, ErrUnknownJob
+	}
+
+	return job.next, nil
+}
+
+// Start loads the state file and starts running jobs in the background
+func (s *RCONScheduler) Start() error {
+	lastRun, err := s.loadState()
+
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return ErrServerClosed
+	}
+
+	if s.started {
+		return nil
+	}
+
+	s.started = true
+	s.lastRun = lastRun
+
+	now := time.Now()
+
+	for _, job := range s.jobs {
+		s.scheduleFirst(job, now)
+	}
+
+	s.wg.Add(1)
+
+	go s.run()
+
+	return nil
+}
+
+// Close stops the scheduler and waits for any runs that have started to finish
+func (s *RCONScheduler) Close() error {
+	s.lock.Lock()
+
+	if s.closed {
+		s.lock.Unlock()
+
+		return ErrServerClosed
+	}
+
+	s.closed = true
+
+	s.lock.Unlock()
+
+	close(s.done)
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *RCONScheduler) run() {
+	defer s.wg.Done()
+
+	for {
+		wait := time.Minute
+
+		s.lock.Lock()
+
+		for _, job := range s.jobs {
+			if job.next.IsZero() {
+				continue
+			}
+
+			if until := time.Until(job.next); until < wait {
+				wait = until
+			}
+		}
+
+		s.lock.Unlock()
+
+		// Timers run on the monotonic clock, the wait is capped so that changes to the wall clock are noticed
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-s.done:
+			{
+				timer.Stop()
+
+				return
+			}
+		case <-s.wake:
+			{
+				timer.Stop()
+
+				continue
+			}
+		case <-timer.C:
+		}
+
+		s.runDue(time.Now())
+	}
+}
+
+// runDue starts every job that is due at the time
+func (s *RCONScheduler) runDue(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, job := range s.jobs {
+		if job.next.IsZero() || now.Before(job.next) {
+			continue
+		}
+
+		scheduled := job.next
+		following := job.schedule.Next(scheduled.In(s.options.Location))
+
+		// A run is missed if the run after it is also due, which means the scheduler was not running at the time
+		if !following.IsZero() && !following.After(now) {
+			job.next = job.schedule.Next(now.In(s.options.Location))
+
+			if job.job.MissedRun == RCONMissedRunSkip {
+				continue
+			}
+		} else {
+			job.next = following
+		}
+
+		if job.running {
+			continue
+		}
+
+		job.running = true
+
+		s.wg.Add(1)
+
+		go (func(job *rconScheduledJob, scheduled time.Time) {
+			defer s.wg.Done()
+
+			s.runJob(job, scheduled)
+		})(job, scheduled)
+	}
+}
+
+// runJob runs the job on each of its servers at the same time and records the run in the state file
+func (s *RCONScheduler) runJob(job *rconScheduledJob, scheduled time.Time) {
+	servers := job.job.Servers
+
+	if len(servers) < 1 {
+		servers = s.pool.Servers()
+	}
+
+	wg := &sync.WaitGroup{}
+
+	for _, server := range servers {
+		wg.Add(1)
+
+		go (func(server string) {
+			defer wg.Done()
+
+			result := s.runOnServer(job.job, server, scheduled)
+
+			if s.options.OnResult != nil {
+				s.options.OnResult(result)
+			}
+		})(server)
+	}
+
+	wg.Wait()
+
+	s.lock.Lock()
+
+	job.running = false
+	s.lastRun[job.job.Name] = scheduled
+
+	s.lock.Unlock()
+
+	if err := s.saveState(); err != nil && s.options.OnResult != nil {
+		s.options.OnResult(RCONJobResult{
+			Job:       job.job.Name,
+			Scheduled: scheduled,
+			Started:   time.Now(),
+			Err:       err,
+		})
+	}
+}
+
+func (s *RCONScheduler) runOnServer(job RCONJob, server string, scheduled time.Time) RCONJobResult {
+	result := RCONJobResult{
+		Job:       job.Name,
+		Server:    server,
+		Scheduled: scheduled,
+		Started:   time.Now(),
+		Outputs:   make([]string, 0, len(job.Commands)),
+	}
+
+	if job.Condition != nil {
+		ok, err := job.Condition(s.pool, server)
+
+		if err != nil {
+			result.Err = err
+
+			return result
+		}
+
+		if !ok {
+			result.Skipped = true
+
+			return result
+		}
+	}
+
+	for _, command := range job.Commands {
+		output, err := s.pool.ExecuteAs(job.Caller, server, command)
+
+		if err != nil {
+			result.Err = err
+
+			return result
+		}
+
+		result.Outputs = append(result.Outputs, output)
+	}
+
+	return result
+}
+
+// scheduleFirst sets the first run of a job after the scheduler starts or the job is added, which is a
+// missed run if the job should run once after missing runs, the scheduler must be locked
+func (s *RCONScheduler) scheduleFirst(job *rconScheduledJob, now time.Time) {
+	if lastRun, ok := s.lastRun[job.job.Name]; ok && job.job.MissedRun == RCONMissedRunOnce {
+		job.next = job.schedule.Next(lastRun.In(s.options.Location))
+
+		return
+	}
+
+	job.next = job.schedule.Next(now.In(s.options.Location))
+}
+
+// notify wakes up the scheduler to recalculate the time until the next run
+func (s *RCONScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *RCONScheduler) loadState() (map[string]time.Time, error) {
+	lastRun := make(map[string]time.Time)
+
+	if len(s.options.StateFile) < 1 {
+		return lastRun, nil
+	}
+
+	data, err := os.ReadFile(s.options.StateFile)
+
+	if os.IsNotExist(err) {
+		return lastRun, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	state := rconSchedulerState{}
+
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	for name, t := range state.LastRun {
+		lastRun[name] = t
+	}
+
+	return lastRun, nil
+}
+
+// saveState writes the state to a temporary file and renames it over the state file, so that the state file
+// is never left partially written
+func (s *RCONScheduler) saveState() error {
+	if len(s.options.StateFile) < 1 {
+		return nil
+	}
+
+	s.saveLock.Lock()
+	defer s.saveLock.Unlock()
+
+	s.lock.Lock()
+
+	state := rconSchedulerState{
+		LastRun: make(map[string]time.Time, len(s.lastRun)),
+	}
+
+	for name, t := range s.lastRun {
+		state.LastRun[name] = t
+	}
+
+	s.lock.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "\t")
+
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.options.StateFile), filepath.Base(s.options.StateFile)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+
+		return err
+	}
+
+	return os.Rename(f.Name(), s.options.StateFile)
+}
+
+// PlayersOnlineCondition returns a condition that runs a job on a server only if at least the number of
+// players are online, according to the list command
+func PlayersOnlineCondition(count int) RCONCondition {
+	return func(pool *RCONPool, server string) (bool, error) {
+		output, err := pool.Execute(server, "list")
+
+		if err != nil {
+			return false, err
+		}
+
+		if err = CheckRCONOutput("list", output); err != nil {
+			return false, err
+		}
+
+		list, err := parseRCONPlayerList("list", output)
+
+		if err != nil {
+			return false, err
+		}
+
+		return list.Online >= count, nil
+	}
+}
+
+// StatusCondition returns a condition that retrieves the status of the server at the host and port, and runs
+// a job only if the check returns true. The server in the pool is not used, as the status is retrieved from
+// the game port rather than over RCON.
+func StatusCondition(host string, port uint16, check func(status *JavaStatusResponse) bool, options ...JavaStatusOptions) RCONCondition {
+	return func(pool *RCONPool, server string) (bool, error) {
+		status, err := Status(host, port, options...)
+
+		if err != nil {
+			return false, err
+		}
+
+		return check(status), nil
+	}
+}
+
+func parseRCONSchedulerOptions(opts ...RCONSchedulerOptions) RCONSchedulerOptions {
+	if len(opts) < 1 {
+		return defaultRCONSchedulerOptions
+	}
+
+	return opts[0]
+}
@@ -0,0 +1,190 @@
+package mcstatus_test
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func startTestSchedulerPool(t *testing.T) *mcstatus.RCONPool {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		if command == "list" {
+			return "There are 0 of a max of 20 players online: "
+		}
+
+		return command
+	})
+
+	go server.Serve(l)
+
+	pool := mcstatus.NewRCONPool()
+
+	t.Cleanup(func() {
+		pool.Close()
+		server.Close()
+	})
+
+	if err = pool.Add("lobby", "127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+func TestRCONScheduler(t *testing.T) {
+	pool := startTestSchedulerPool(t)
+	results := make(chan mcstatus.RCONJobResult, 16)
+
+	scheduler := mcstatus.NewRCONScheduler(pool, mcstatus.RCONSchedulerOptions{
+		OnResult: func(result mcstatus.RCONJobResult) {
+			results <- result
+		},
+	})
+
+	if err := scheduler.Add(mcstatus.RCONJob{Name: "announce", Schedule: "* * * * * *", Commands: []string{"say Restarting soon", "save-all"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.Add(mcstatus.RCONJob{Name: "announce", Schedule: "@hourly"}); err != mcstatus.ErrJobExists {
+		t.Fatalf("expected ErrJobExists, got %v", err)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-results
+	second := <-results
+
+	if err := scheduler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Err != nil || first.Server != "lobby" || len(first.Outputs) != 2 || first.Outputs[1] != "save-all" {
+		t.Fatalf("unexpected result: %+v", first)
+	}
+
+	// Runs start on whole seconds, each exactly one second after the last
+	if first.Scheduled.Nanosecond() != 0 || second.Scheduled.Sub(first.Scheduled) != time.Second {
+		t.Fatalf("unexpected scheduled times: %s, %s", first.Scheduled, second.Scheduled)
+	}
+
+	if first.Started.Before(first.Scheduled) {
+		t.Fatalf("run started at %s before it was scheduled at %s", first.Started, first.Scheduled)
+	}
+}
+
+func TestRCONSchedulerCondition(t *testing.T) {
+	pool := startTestSchedulerPool(t)
+	results := make(chan mcstatus.RCONJobResult, 16)
+
+	scheduler := mcstatus.NewRCONScheduler(pool, mcstatus.RCONSchedulerOptions{
+		OnResult: func(result mcstatus.RCONJobResult) {
+			results <- result
+		},
+	})
+
+	defer scheduler.Close()
+
+	if err := scheduler.Add(mcstatus.RCONJob{Name: "event", Schedule: "* * * * * *", Commands: []string{"say Event starting"}, Condition: mcstatus.PlayersOnlineCondition(1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := <-results; !result.Skipped || result.Err != nil || len(result.Outputs) != 0 {
+		t.Fatalf("expected the run to be skipped with no players online, got %+v", result)
+	}
+}
+
+func TestRCONSchedulerMissedRuns(t *testing.T) {
+	pool := startTestSchedulerPool(t)
+	results := make(chan mcstatus.RCONJobResult, 16)
+	stateFile := filepath.Join(t.TempDir(), "scheduler.json")
+	lastRun := time.Now().Add(-time.Hour * 3).Truncate(time.Hour)
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"last_run": map[string]time.Time{
+			"save":     lastRun,
+			"announce": lastRun,
+		},
+	})
+
+	if err := os.WriteFile(stateFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := mcstatus.NewRCONScheduler(pool, mcstatus.RCONSchedulerOptions{
+		StateFile: stateFile,
+		Location:  time.UTC,
+		OnResult: func(result mcstatus.RCONJobResult) {
+			results <- result
+		},
+	})
+
+	if err := scheduler.Add(mcstatus.RCONJob{Name: "save", Schedule: "@hourly", Commands: []string{"save-all"}, MissedRun: mcstatus.RCONMissedRunOnce}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.Add(mcstatus.RCONJob{Name: "announce", Schedule: "@hourly", Commands: []string{"say Hourly"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the job that runs once after missing runs is run, and only once
+	var result mcstatus.RCONJobResult
+
+	select {
+	case result = <-results:
+	case <-time.After(time.Second * 5):
+		t.Fatal("missed job was not run")
+	}
+
+	if result.Job != "save" || !result.Scheduled.Equal(lastRun.Add(time.Hour)) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	select {
+	case result = <-results:
+		t.Fatalf("unexpected result: %+v", result)
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	for _, name := range []string{"save", "announce"} {
+		if next, _ := scheduler.Next(name); !next.After(time.Now()) {
+			t.Fatalf("expected %s to be scheduled in the future, got %s", name, next)
+		}
+	}
+
+	if err := scheduler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	state := struct {
+		LastRun map[string]time.Time `json:"last_run"`
+	}{}
+
+	if data, err := os.ReadFile(stateFile); err != nil || json.Unmarshal(data, &state) != nil {
+		t.Fatalf("failed to read the state file: %v", err)
+	}
+
+	if !state.LastRun["save"].Equal(lastRun.Add(time.Hour)) || !state.LastRun["announce"].Equal(lastRun) {
+		t.Fatalf("unexpected state: %+v", state.LastRun)
+	}
+}
@@ -0,0 +1,230 @@
+package mcstatus
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	defaultRCONServerOptions = RCONServerOptions{
+		Timeout:         time.Second * 10,
+		MaxResponseSize: 4096,
+	}
+)
+
+// RCONHandler runs a command received by an RCONServer from the address and returns the output of the command
+type RCONHandler func(addr net.Addr, command string) string
+
+type RCONServerOptions struct {
+	Timeout         time.Duration
+	MaxResponseSize int
+	Mode            RCONMode
+	MaxPacketSize   int
+}
+
+// RCONServer is a server for the RCON protocol that passes commands to a handler, behaving the same as a
+// vanilla server. Each connection must log in with the password before it can run commands, and commands
+// on a connection are handled one at a time in the order they were received. Output longer than the maximum
+// response size is split into multiple packets, and packets of an unknown type are answered with an
+// "Unknown request" message, which clients use to find the end of the output. In Source mode the server
+// behaves as a Source engine server instead.
+type RCONServer struct {
+	password string
+	handler  RCONHandler
+	options  RCONServerOptions
+	server   tcpServer
+}
+
+// NewRCONServer creates a new RCON server that runs commands with the handler
+func NewRCONServer(password string, handler RCONHandler, options ...RCONServerOptions) *RCONServer {
+	return &RCONServer{
+		password: password,
+		handler:  handler,
+		options:  parseRCONServerOptions(options...),
+	}
+}
+
+// ListenAndServe listens on the TCP address and handles every accepted connection
+func (s *RCONServer) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts connections from the listener and handles them until the server is closed
+func (s *RCONServer) Serve(l net.Listener) error {
+	return s.server.serve(l, s.handle)
+}
+
+// Close stops accepting connections and closes all open connections
+func (s *RCONServer) Close() error {
+	return s.server.close()
+}
+
+func (s *RCONServer) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	authenticated := false
+
+	maxPacketSize := RCONOptions{Mode: s.options.Mode, MaxPacketSize: s.options.MaxPacketSize}.maxPacketSize()
+	maxResponseSize := s.options.MaxResponseSize
+
+	if maxResponseSize > maxPacketSize-14 {
+		maxResponseSize = maxPacketSize - 14
+	}
+
+	for {
+		// Connections are given the timeout to log in, after which they may stay idle
+		if !authenticated {
+			if err := conn.SetReadDeadline(time.Now().Add(s.options.Timeout)); err != nil {
+				return
+			}
+		}
+
+		packet, err := readRCONPacket(r, maxPacketSize)
+
+		if err != nil {
+			return
+		}
+
+		switch packet.Type {
+		case rconTypeLogin:
+			{
+				authenticated = subtle.ConstantTimeCompare([]byte(packet.Payload), []byte(s.password)) == 1
+
+				requestID := packet.RequestID
+
+				if !authenticated {
+					requestID = -1
+				}
+
+				// Source servers send an empty response before the login response
+				if s.options.Mode == RCONModeSource {
+					if err = s.write(conn, rconPacket{RequestID: packet.RequestID, Type: rconTypeResponse}, maxPacketSize); err != nil {
+						return
+					}
+				}
+
+				if err = s.write(conn, rconPacket{RequestID: requestID, Type: rconTypeCommand}, maxPacketSize); err != nil {
+					return
+				}
+
+				if authenticated {
+					if err = conn.SetReadDeadline(time.Time{}); err != nil {
+						return
+					}
+				}
+			}
+		case rconTypeCommand:
+			{
+				if !authenticated {
+					if err = s.write(conn, rconPacket{RequestID: -1, Type: rconTypeCommand}, maxPacketSize); err != nil {
+						return
+					}
+
+					continue
+				}
+
+				output := s.handler(conn.RemoteAddr(), packet.Payload)
+
+				for _, chunk := range splitRCONResponse(output, maxResponseSize) {
+					if err = s.write(conn, rconPacket{RequestID: packet.RequestID, Type: rconTypeResponse, Payload: chunk}, maxPacketSize); err != nil {
+						return
+					}
+				}
+			}
+		case rconTypeResponse:
+			{
+				if s.options.Mode != RCONModeSource {
+					if err = s.write(conn, rconPacket{RequestID: packet.RequestID, Type: rconTypeResponse, Payload: "Unknown request 0"}, maxPacketSize); err != nil {
+						return
+					}
+
+					continue
+				}
+
+				// Source servers mirror an empty response, followed by a second packet with a fixed body
+				// https://developer.valvesoftware.com/wiki/Source_RCON_Protocol#Multiple-packet_Responses
+				if err = s.write(conn, rconPacket{RequestID: packet.RequestID, Type: rconTypeResponse}, maxPacketSize); err != nil {
+					return
+				}
+
+				if err = s.write(conn, rconPacket{RequestID: packet.RequestID, Type: rconTypeResponse, Payload: "\x00\x00\x00\x01\x00\x00\x00\x00"}, maxPacketSize); err != nil {
+					return
+				}
+			}
+		default:
+			{
+				if err = s.write(conn, rconPacket{RequestID: packet.RequestID, Type: rconTypeResponse, Payload: fmt.Sprintf("Unknown request %x", packet.Type)}, maxPacketSize); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *RCONServer) write(conn net.Conn, packet rconPacket, maxPacketSize int) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(s.options.Timeout)); err != nil {
+		return err
+	}
+
+	return writeRCONPacket(conn, packet, maxPacketSize)
+}
+
+// splitRCONResponse splits the output into chunks of at most the size in bytes, without splitting any
+// characters. Empty output is still sent as a single empty chunk.
+func splitRCONResponse(output string, size int) []string {
+	if size < utf8.UTFMax {
+		size = utf8.UTFMax
+	}
+
+	chunks := make([]string, 0, len(output)/size+1)
+
+	for len(output) > size {
+		end := size
+
+		for end > 0 && !utf8.RuneStart(output[end]) {
+			end--
+		}
+
+		// Invalid UTF-8 without any character boundary is split at the size instead
+		if end < 1 {
+			end = size
+		}
+
+		chunks = append(chunks, output[:end])
+		output = output[end:]
+	}
+
+	return append(chunks, output)
+}
+
+func parseRCONServerOptions(opts ...RCONServerOptions) RCONServerOptions {
+	if len(opts) < 1 {
+		return defaultRCONServerOptions
+	}
+
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultRCONServerOptions.Timeout
+	}
+
+	if options.MaxResponseSize <= 0 {
+		options.MaxResponseSize = defaultRCONServerOptions.MaxResponseSize
+	}
+
+	// A packet must fit the 14 byte header along with at least one character of output
+	if options.MaxPacketSize > 0 && options.MaxPacketSize < 14+utf8.UTFMax {
+		options.MaxPacketSize = 14 + utf8.UTFMax
+	}
+
+	return options
+}
@@ -0,0 +1,156 @@
+package mcstatus_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func readTestRCONPacket(t *testing.T, r io.Reader) (int32, int32, string) {
+	var length, requestID, packetType int32
+
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		t.Fatal(err)
+	}
+
+	binary.Read(r, binary.LittleEndian, &requestID)
+	binary.Read(r, binary.LittleEndian, &packetType)
+
+	data := make([]byte, length-8)
+
+	if _, err := io.ReadFull(r, data); err != nil {
+		t.Fatal(err)
+	}
+
+	return requestID, packetType, strings.TrimRight(string(data), "\x00")
+}
+
+func TestRCONServer(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		return strings.Repeat("é", 3000)
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	conn, err := net.Dial("tcp4", l.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+
+	// Commands before logging in are rejected
+	writeTestRCONPacket(conn, 5, 2, "list")
+
+	if requestID, _, _ := readTestRCONPacket(t, conn); requestID != -1 {
+		t.Fatalf("expected request ID -1 before login, got %d", requestID)
+	}
+
+	writeTestRCONPacket(conn, 6, 3, "wrong")
+
+	if requestID, packetType, _ := readTestRCONPacket(t, conn); requestID != -1 || packetType != 2 {
+		t.Fatalf("expected an authentication failure, got %d %d", requestID, packetType)
+	}
+
+	writeTestRCONPacket(conn, 7, 3, "password")
+
+	if requestID, _, _ := readTestRCONPacket(t, conn); requestID != 7 {
+		t.Fatalf("expected request ID 7 after login, got %d", requestID)
+	}
+
+	// 6000 bytes of output is split into two packets, without splitting a character
+	writeTestRCONPacket(conn, 8, 2, "list")
+	writeTestRCONPacket(conn, 9, 0, "")
+
+	output := ""
+
+	for i := 0; i < 2; i++ {
+		requestID, packetType, payload := readTestRCONPacket(t, conn)
+
+		if requestID != 8 || packetType != 0 || len(payload) > 4096 {
+			t.Fatalf("unexpected packet: %d %d, %d bytes", requestID, packetType, len(payload))
+		}
+
+		output += payload
+	}
+
+	if output != strings.Repeat("é", 3000) {
+		t.Fatal("output was not split on character boundaries")
+	}
+
+	if requestID, _, payload := readTestRCONPacket(t, conn); requestID != 9 || payload != "Unknown request 0" {
+		t.Fatalf("unexpected sentinel response: %d %q", requestID, payload)
+	}
+}
+
+func TestRCONServerDefaultOptions(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the max packet size is set, every other option uses its default
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		return strings.Repeat("a", 6000)
+	}, mcstatus.RCONServerOptions{MaxPacketSize: 1 << 16})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	conn, err := net.Dial("tcp4", l.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+
+	writeTestRCONPacket(conn, 7, 3, "password")
+
+	if requestID, _, _ := readTestRCONPacket(t, conn); requestID != 7 {
+		t.Fatalf("expected request ID 7 after login, got %d", requestID)
+	}
+
+	writeTestRCONPacket(conn, 8, 2, "list")
+	writeTestRCONPacket(conn, 9, 0, "")
+
+	packets := 0
+
+	for {
+		requestID, _, payload := readTestRCONPacket(t, conn)
+
+		if requestID == 9 {
+			break
+		}
+
+		if len(payload) > 4096 {
+			t.Fatalf("unexpected packet: %d bytes", len(payload))
+		}
+
+		packets++
+	}
+
+	if packets != 2 {
+		t.Fatalf("expected the output to be split into 2 packets, got %d", packets)
+	}
+}
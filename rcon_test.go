@@ -1,17 +1,67 @@
 package mcstatus_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
 )
 
+// testRCONHandler echoes commands back, except for "long" which responds with output that is split into
+// multiple packets
+func testRCONHandler(addr net.Addr, command string) string {
+	if command == "long" {
+		return strings.Repeat("a", 10000)
+	}
+
+	return command
+}
+
+func startTestRCON(t *testing.T) uint16 {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", testRCONHandler)
+
+	go server.Serve(l)
+
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	return uint16(l.Addr().(*net.TCPAddr).Port)
+}
+
+func writeTestRCONPacket(w io.Writer, requestID, packetType int32, payload string) {
+	buf := &bytes.Buffer{}
+
+	binary.Write(buf, binary.LittleEndian, int32(10+len(payload)))
+	binary.Write(buf, binary.LittleEndian, requestID)
+	binary.Write(buf, binary.LittleEndian, packetType)
+	buf.WriteString(payload + "\x00\x00")
+
+	w.Write(buf.Bytes())
+}
+
 func TestRCON(t *testing.T) {
-	/* client := mcstatus.NewRCON()
+	port := startTestRCON(t)
 
-	if err := client.Dial("localhost", 25575); err != nil {
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", port); err != nil {
 		t.Fatal(err)
 	}
 
-	if err := client.Login("abc123"); err != nil {
+	if err := client.Login("password"); err != nil {
 		t.Fatal(err)
 	}
 
@@ -19,18 +69,257 @@ func TestRCON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := client.Run("tps"); err != nil {
+	if err := client.Run("say Hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	if message := <-client.Messages; message != "time query daytime" {
+		t.Fatalf("unexpected message: %q", message)
+	}
+
+	if message := <-client.Messages; message != "say Hi" {
+		t.Fatalf("unexpected message: %q", message)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRCONExecute(t *testing.T) {
+	port := startTestRCON(t)
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", port, mcstatus.RCONOptions{Timeout: time.Second * 5}); err != nil {
 		t.Fatal(err)
 	}
 
+	defer client.Close()
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := client.Execute("long")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != strings.Repeat("a", 10000) {
+		t.Fatalf("expected fragmented output to be reassembled, got %d bytes", len(output))
+	}
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+
+		go (func(command string) {
+			defer wg.Done()
+
+			output, err := client.Execute(command)
+
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			if output != command {
+				t.Errorf("expected output %q, got %q", command, output)
+			}
+		})(strings.Repeat("x", i+1))
+	}
+
+	wg.Wait()
+
 	if err := client.Run("say Hi"); err != nil {
 		t.Fatal(err)
 	}
 
-	fmt.Println(<-client.Messages)
-	fmt.Println(<-client.Messages)
+	if message := <-client.Messages; message != "say Hi" {
+		t.Fatalf("unexpected message: %q", message)
+	}
 
-	if err := client.Close(); err != nil {
+	// The echo handler returns the command, so the formatting codes are parsed from the output
+	motd, err := client.ExecuteFormatted("\u00A7cPlugins (2): \u00A7x\u00A7f\u00A7f\u00A78\u00A70\u00A70\u00A70Essentials")
+
+	if err != nil {
 		t.Fatal(err)
-	} */
+	}
+
+	if motd.Clean() != "Plugins (2): Essentials" || motd.Tree[0].Color != "red" || motd.Tree[1].Color != "#ff8000" {
+		t.Fatalf("unexpected formatted output: %+v", motd.Tree)
+	}
+}
+
+func TestRCONInvalidPassword(t *testing.T) {
+	port := startTestRCON(t)
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", port); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err := client.Login("wrong"); err != mcstatus.ErrInvalidPassword {
+		t.Fatalf("expected ErrInvalidPassword, got %v", err)
+	}
+}
+
+func TestRCONConnectionLost(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	go (func() {
+		conn, err := l.Accept()
+
+		if err != nil {
+			return
+		}
+
+		// Accept the login, then close the connection without answering the command
+		data := make([]byte, 64)
+		conn.Read(data)
+
+		writeTestRCONPacket(conn, 0, 2, "")
+
+		conn.Read(data)
+		conn.Close()
+	})()
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Execute("list"); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	if _, err := client.Execute("list"); err != io.EOF {
+		t.Fatalf("expected the connection error to be kept, got %v", err)
+	}
+}
+
+func TestRCONSourceMode(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", testRCONHandler, mcstatus.RCONServerOptions{
+		Timeout:         time.Second * 5,
+		MaxResponseSize: 4096,
+		Mode:            mcstatus.RCONModeSource,
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port), mcstatus.RCONOptions{
+		Timeout: time.Second * 5,
+		Mode:    mcstatus.RCONModeSource,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		output, err := client.Execute("long")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output != strings.Repeat("a", 10000) {
+			t.Fatalf("expected fragmented output to be reassembled, got %d bytes", len(output))
+		}
+	}
+
+	// Packets longer than the max packet size of Source servers are not sent
+	if _, err := client.Execute(strings.Repeat("x", 5000)); err != mcstatus.ErrPacketTooLarge {
+		t.Fatalf("expected ErrPacketTooLarge, got %v", err)
+	}
+
+	// The second response to each sentinel must not leak into the messages of Run
+	select {
+	case message := <-client.Messages:
+		t.Fatalf("unexpected message: %q", message)
+	default:
+	}
+}
+
+func TestRCONSinglePacketTerminator(t *testing.T) {
+	port := startTestRCON(t)
+
+	client := mcstatus.NewRCON()
+
+	if err := client.Dial("127.0.0.1", port, mcstatus.RCONOptions{
+		Timeout:    time.Second * 5,
+		Terminator: mcstatus.RCONTerminatorSinglePacket,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := client.Execute("list"); err != nil || output != "list" {
+		t.Fatalf("unexpected result: %q, %v", output, err)
+	}
+
+	// Only the first packet of fragmented output is returned without a sentinel
+	if output, err := client.Execute("long"); err != nil || len(output) != 4096 {
+		t.Fatalf("unexpected result: %d bytes, %v", len(output), err)
+	}
+}
+
+func TestRCONDefaultOptions(t *testing.T) {
+	port := startTestRCON(t)
+
+	client := mcstatus.NewRCON()
+
+	// Only the mode is set, every other option uses its default
+	if err := client.Dial("127.0.0.1", port, mcstatus.RCONOptions{Mode: mcstatus.RCONModeMinecraft}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err := client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := client.Execute("long"); err != nil || output != strings.Repeat("a", 10000) {
+		t.Fatalf("unexpected result: %d bytes, %v", len(output), err)
+	}
 }
@@ -0,0 +1,254 @@
+package mcstatus
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// tcpServer tracks a listener and every connection that has been opened on behalf of it, so that all
+// of them can be closed together when the server shuts down
+type tcpServer struct {
+	listener net.Listener
+	closed   bool
+	conns    map[net.Conn]struct{}
+	lock     sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// serve accepts connections from the listener and runs the handler for each one until the server is closed
+func (s *tcpServer) serve(l net.Listener, handler func(net.Conn)) error {
+	s.lock.Lock()
+
+	if s.closed {
+		s.lock.Unlock()
+
+		return ErrServerClosed
+	}
+
+	s.listener = l
+
+	s.lock.Unlock()
+
+	for {
+		conn, err := l.Accept()
+
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			var netErr net.Error
+
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				time.Sleep(time.Millisecond * 10)
+
+				continue
+			}
+
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+
+			return nil
+		}
+
+		s.wg.Add(1)
+
+		go (func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			defer conn.Close()
+
+			handler(conn)
+		})()
+	}
+}
+
+// close stops accepting connections, closes all tracked connections and waits for their handlers to return
+func (s *tcpServer) close() error {
+	s.lock.Lock()
+
+	s.closed = true
+
+	var err error
+
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+
+	s.lock.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+// track registers a connection to be closed with the server, it returns false if the server is already closed
+func (s *tcpServer) track(conn net.Conn) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+
+	s.conns[conn] = struct{}{}
+
+	return true
+}
+
+func (s *tcpServer) untrack(conn net.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.conns, conn)
+}
+
+// udpServer reads datagrams from a packet connection and runs the handler for each one until the server is
+// closed, the data passed to the handler is only valid until the handler returns
+type udpServer struct {
+	conn   net.PacketConn
+	closed bool
+	lock   sync.Mutex
+}
+
+// serve reads datagrams from the connection and runs the handler for each one until the server is closed
+func (s *udpServer) serve(conn net.PacketConn, handler func(conn net.PacketConn, data []byte, addr net.Addr)) error {
+	s.lock.Lock()
+
+	if s.closed {
+		s.lock.Unlock()
+
+		return ErrServerClosed
+	}
+
+	s.conn = conn
+
+	s.lock.Unlock()
+
+	data := make([]byte, 1<<16)
+
+	for {
+		n, addr, err := conn.ReadFrom(data)
+
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			var netErr net.Error
+
+			if errors.As(err, &netErr) && (netErr.Temporary() || netErr.Timeout()) {
+				time.Sleep(time.Millisecond * 10)
+
+				continue
+			}
+
+			return err
+		}
+
+		handler(conn, data[:n], addr)
+	}
+}
+
+// close stops reading datagrams and closes the connection
+func (s *udpServer) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+
+	return nil
+}
+
+// rateLimiter is a token bucket rate limiter with a separate bucket for every key, the zero value allows everything
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	buckets   map[string]*rateBucket
+	lastPrune time.Time
+	lock      sync.Mutex
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket of the key and returns false if the bucket is empty
+func (l *rateLimiter) allow(key string) bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*rateBucket)
+	}
+
+	// Buckets that have refilled completely are the same as a new bucket, so they are dropped from time to time
+	// to keep the map from growing with every address that has ever sent a request
+	if now.Sub(l.lastPrune) > time.Minute {
+		for k, bucket := range l.buckets {
+			if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+
+		l.lastPrune = now
+	}
+
+	bucket, ok := l.buckets[key]
+
+	if !ok {
+		bucket = &rateBucket{
+			tokens: l.burst,
+			last:   now,
+		}
+
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+	bucket.last = now
+
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
@@ -0,0 +1,536 @@
+package mcstatus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxSNBTDepth = 512
+
+var (
+	snbtDoubleRegExp         = regexp.MustCompile(`(?i)^[-+]?(?:[0-9]+[.]?|[0-9]*[.][0-9]+)(?:e[-+]?[0-9]+)?d$`)
+	snbtDoubleNoSuffixRegExp = regexp.MustCompile(`(?i)^[-+]?(?:[0-9]+[.]|[0-9]*[.][0-9]+)(?:e[-+]?[0-9]+)?$`)
+	snbtFloatRegExp          = regexp.MustCompile(`(?i)^[-+]?(?:[0-9]+[.]?|[0-9]*[.][0-9]+)(?:e[-+]?[0-9]+)?f$`)
+	snbtByteRegExp           = regexp.MustCompile(`(?i)^[-+]?(?:0|[1-9][0-9]*)b$`)
+	snbtShortRegExp          = regexp.MustCompile(`(?i)^[-+]?(?:0|[1-9][0-9]*)s$`)
+	snbtLongRegExp           = regexp.MustCompile(`(?i)^[-+]?(?:0|[1-9][0-9]*)l$`)
+	snbtIntRegExp            = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)$`)
+	dataGetOutputRegExp      = regexp.MustCompile(`(?s)^.+? has the following (?:entity data|block data|contents): (.*)$`)
+)
+
+// NBTCompound is a parsed SNBT compound. The values are NBTCompound, NBTList, string, int8 (byte), int16 (short),
+// int32 (int), int64 (long), float32 (float), float64 (double), []int8, []int32 or []int64.
+type NBTCompound map[string]interface{}
+
+// NBTList is a parsed SNBT list, all values in a list have the same type
+type NBTList []interface{}
+
+// ParseSNBT parses stringified NBT, as printed by the data command, into Go values. See NBTCompound for the
+// types of the values. Booleans are parsed as bytes, the same as the game does.
+func ParseSNBT(value string) (interface{}, error) {
+	p := &snbtParser{data: value}
+
+	result, err := p.readValue(0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipWhitespace()
+
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected trailing data")
+	}
+
+	return result, nil
+}
+
+// ParseSNBTCompound parses stringified NBT that must be a compound
+func ParseSNBTCompound(value string) (NBTCompound, error) {
+	result, err := ParseSNBT(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	compound, ok := result.(NBTCompound)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a compound, got %T", ErrInvalidSNBT, result)
+	}
+
+	return compound, nil
+}
+
+// ParseDataGetOutput parses the output of a data get command, such as "Notch has the following entity data: {...}"
+func ParseDataGetOutput(output string) (interface{}, error) {
+	match := dataGetOutputRegExp.FindStringSubmatch(output)
+
+	if match == nil {
+		return nil, ErrUnexpectedResponse
+	}
+
+	return ParseSNBT(match[1])
+}
+
+// DataGetEntity returns the NBT data of the entity, such as the Pos, Health and Inventory of a player
+func (r *RCON) DataGetEntity(target string) (NBTCompound, error) {
+	return r.DataGetEntityAs(RCONCaller{}, target)
+}
+
+// DataGetEntityAs is the same as DataGetEntity, but runs the command as the caller
+func (r *RCON) DataGetEntityAs(caller RCONCaller, target string) (NBTCompound, error) {
+	return r.dataGet(caller, "data get entity "+target)
+}
+
+// DataGetBlock returns the NBT data of the block entity at the position
+func (r *RCON) DataGetBlock(x, y, z int) (NBTCompound, error) {
+	return r.DataGetBlockAs(RCONCaller{}, x, y, z)
+}
+
+// DataGetBlockAs is the same as DataGetBlock, but runs the command as the caller
+func (r *RCON) DataGetBlockAs(caller RCONCaller, x, y, z int) (NBTCompound, error) {
+	return r.dataGet(caller, fmt.Sprintf("data get block %d %d %d", x, y, z))
+}
+
+// DataGetStorage returns the NBT data of the command storage with the ID, such as "minecraft:example"
+func (r *RCON) DataGetStorage(id string) (NBTCompound, error) {
+	return r.DataGetStorageAs(RCONCaller{}, id)
+}
+
+// DataGetStorageAs is the same as DataGetStorage, but runs the command as the caller
+func (r *RCON) DataGetStorageAs(caller RCONCaller, id string) (NBTCompound, error) {
+	return r.dataGet(caller, "data get storage "+id)
+}
+
+func (r *RCON) dataGet(caller RCONCaller, command string) (NBTCompound, error) {
+	output, err := r.executeChecked(caller, command)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ParseDataGetOutput(output)
+
+	if err == ErrUnexpectedResponse {
+		return nil, unexpectedRCONOutput(command, output)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	compound, ok := result.(NBTCompound)
+
+	if !ok {
+		return nil, unexpectedRCONOutput(command, output)
+	}
+
+	return compound, nil
+}
+
+// Compound returns the value of the key if it is a compound
+func (c NBTCompound) Compound(key string) (NBTCompound, bool) {
+	v, ok := c[key].(NBTCompound)
+
+	return v, ok
+}
+
+// List returns the value of the key if it is a list
+func (c NBTCompound) List(key string) (NBTList, bool) {
+	v, ok := c[key].(NBTList)
+
+	return v, ok
+}
+
+// String returns the value of the key if it is a string
+func (c NBTCompound) String(key string) (string, bool) {
+	v, ok := c[key].(string)
+
+	return v, ok
+}
+
+// Int returns the value of the key if it is a byte, short, int or long
+func (c NBTCompound) Int(key string) (int64, bool) {
+	switch v := c[key].(type) {
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// Float returns the value of the key if it is a number of any type
+func (c NBTCompound) Float(key string) (float64, bool) {
+	switch v := c[key].(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	i, ok := c.Int(key)
+
+	return float64(i), ok
+}
+
+type snbtParser struct {
+	data string
+	pos  int
+}
+
+func (p *snbtParser) readValue(depth int) (interface{}, error) {
+	if depth > maxSNBTDepth {
+		return nil, p.errorf("exceeded the max depth of %d", maxSNBTDepth)
+	}
+
+	p.skipWhitespace()
+
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch p.data[p.pos] {
+	case '{':
+		return p.readCompound(depth)
+	case '[':
+		return p.readList(depth)
+	case '"', '\'':
+		return p.readQuoted()
+	}
+
+	token := p.readUnquoted()
+
+	if len(token) < 1 {
+		return nil, p.errorf("expected a value")
+	}
+
+	return parseSNBTPrimitive(token), nil
+}
+
+func (p *snbtParser) readCompound(depth int) (NBTCompound, error) {
+	// Opening brace
+	p.pos++
+
+	result := make(NBTCompound)
+
+	p.skipWhitespace()
+
+	if p.peek('}') {
+		p.pos++
+
+		return result, nil
+	}
+
+	for {
+		key, err := p.readKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = p.expect(':'); err != nil {
+			return nil, err
+		}
+
+		value, err := p.readValue(depth + 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result[key] = value
+
+		done, err := p.readSeparator('}')
+
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			return result, nil
+		}
+	}
+}
+
+func (p *snbtParser) readList(depth int) (interface{}, error) {
+	// Opening bracket
+	p.pos++
+
+	// Typed arrays start with the type of their elements, such as [I; 1, 2, 3]
+	if p.pos+1 < len(p.data) && p.data[p.pos+1] == ';' {
+		arrayType := p.data[p.pos]
+
+		if arrayType == 'B' || arrayType == 'I' || arrayType == 'L' {
+			p.pos += 2
+
+			return p.readArray(arrayType)
+		}
+	}
+
+	result := make(NBTList, 0)
+
+	p.skipWhitespace()
+
+	if p.peek(']') {
+		p.pos++
+
+		return result, nil
+	}
+
+	for {
+		value, err := p.readValue(depth + 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, value)
+
+		done, err := p.readSeparator(']')
+
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			return result, nil
+		}
+	}
+}
+
+func (p *snbtParser) readArray(arrayType byte) (interface{}, error) {
+	values := make([]int64, 0)
+
+	p.skipWhitespace()
+
+	if p.peek(']') {
+		p.pos++
+	} else {
+		for {
+			p.skipWhitespace()
+
+			start := p.pos
+			token := p.readUnquoted()
+			value := parseSNBTPrimitive(token)
+
+			var v int64
+
+			switch n := value.(type) {
+			case int8:
+				v = int64(n)
+			case int16:
+				v = int64(n)
+			case int32:
+				v = int64(n)
+			case int64:
+				v = n
+			default:
+				{
+					p.pos = start
+
+					return nil, p.errorf("invalid element of %c array: %q", arrayType, token)
+				}
+			}
+
+			values = append(values, v)
+
+			done, err := p.readSeparator(']')
+
+			if err != nil {
+				return nil, err
+			}
+
+			if done {
+				break
+			}
+		}
+	}
+
+	switch arrayType {
+	case 'B':
+		{
+			result := make([]int8, len(values))
+
+			for i, v := range values {
+				result[i] = int8(v)
+			}
+
+			return result, nil
+		}
+	case 'I':
+		{
+			result := make([]int32, len(values))
+
+			for i, v := range values {
+				result[i] = int32(v)
+			}
+
+			return result, nil
+		}
+	}
+
+	return values, nil
+}
+
+func (p *snbtParser) readKey() (string, error) {
+	p.skipWhitespace()
+
+	if p.pos < len(p.data) && (p.data[p.pos] == '"' || p.data[p.pos] == '\'') {
+		return p.readQuoted()
+	}
+
+	key := p.readUnquoted()
+
+	if len(key) < 1 {
+		return "", p.errorf("expected a key")
+	}
+
+	return key, nil
+}
+
+func (p *snbtParser) readQuoted() (string, error) {
+	quote := p.data[p.pos]
+	p.pos++
+
+	result := &strings.Builder{}
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case quote:
+			return result.String(), nil
+		case '\\':
+			{
+				if p.pos >= len(p.data) {
+					return "", p.errorf("unterminated string")
+				}
+
+				// Only quotes and backslashes can be escaped
+				result.WriteByte(p.data[p.pos])
+				p.pos++
+			}
+		default:
+			result.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *snbtParser) readUnquoted() string {
+	start := p.pos
+
+	for p.pos < len(p.data) && isSNBTUnquotedChar(p.data[p.pos]) {
+		p.pos++
+	}
+
+	return p.data[start:p.pos]
+}
+
+// readSeparator reads a comma or the closing character, returning true if it was the closing character
+func (p *snbtParser) readSeparator(closing byte) (bool, error) {
+	p.skipWhitespace()
+
+	if p.pos >= len(p.data) {
+		return false, p.errorf("expected ',' or '%c'", closing)
+	}
+
+	switch p.data[p.pos] {
+	case ',':
+		{
+			p.pos++
+
+			return false, nil
+		}
+	case closing:
+		{
+			p.pos++
+
+			return true, nil
+		}
+	}
+
+	return false, p.errorf("expected ',' or '%c'", closing)
+}
+
+func (p *snbtParser) expect(c byte) error {
+	p.skipWhitespace()
+
+	if !p.peek(c) {
+		return p.errorf("expected '%c'", c)
+	}
+
+	p.pos++
+
+	return nil
+}
+
+func (p *snbtParser) peek(c byte) bool {
+	return p.pos < len(p.data) && p.data[p.pos] == c
+}
+
+func (p *snbtParser) skipWhitespace() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t' || p.data[p.pos] == '\n' || p.data[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *snbtParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrInvalidSNBT, p.pos, fmt.Sprintf(format, args...))
+}
+
+// parseSNBTPrimitive parses an unquoted value as a number or a boolean, or returns it as a string if it is
+// neither. Numbers that are out of range for their type are also strings, the same as the game.
+func parseSNBTPrimitive(token string) interface{} {
+	switch {
+	case snbtByteRegExp.MatchString(token):
+		if v, err := strconv.ParseInt(token[:len(token)-1], 10, 8); err == nil {
+			return int8(v)
+		}
+	case snbtShortRegExp.MatchString(token):
+		if v, err := strconv.ParseInt(token[:len(token)-1], 10, 16); err == nil {
+			return int16(v)
+		}
+	case snbtLongRegExp.MatchString(token):
+		if v, err := strconv.ParseInt(token[:len(token)-1], 10, 64); err == nil {
+			return v
+		}
+	case snbtIntRegExp.MatchString(token):
+		if v, err := strconv.ParseInt(token, 10, 32); err == nil {
+			return int32(v)
+		}
+	case snbtFloatRegExp.MatchString(token):
+		if v, err := strconv.ParseFloat(token[:len(token)-1], 32); err == nil {
+			return float32(v)
+		}
+	case snbtDoubleRegExp.MatchString(token):
+		if v, err := strconv.ParseFloat(token[:len(token)-1], 64); err == nil {
+			return v
+		}
+	case snbtDoubleNoSuffixRegExp.MatchString(token):
+		if v, err := strconv.ParseFloat(token, 64); err == nil {
+			return v
+		}
+	case strings.EqualFold(token, "true"):
+		return int8(1)
+	case strings.EqualFold(token, "false"):
+		return int8(0)
+	}
+
+	return token
+}
+
+func isSNBTUnquotedChar(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '-' || c == '.' || c == '+'
+}
@@ -0,0 +1,114 @@
+package mcstatus_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func TestParseSNBT(t *testing.T) {
+	compound, err := mcstatus.ParseSNBTCompound(`{Health: 20.0f, "quoted key": 'it\'s', foodLevel: 20, XpTotal: 7L, OnGround: 1b, Air: 300s, Pos: [0.5d, 64.0d, -12.5d], UUID: [I; 1, -2, 3], Empty: [], Flag: true, Inventory: [{Slot: 0b, id: "minecraft:stone", count: 64}], Nested: {a: {b: [B; 1b, 2b]}}, Longs: [L; 1L], Big: 3000000000}`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := mcstatus.NBTCompound{
+		"Health":     float32(20),
+		"quoted key": "it's",
+		"foodLevel":  int32(20),
+		"XpTotal":    int64(7),
+		"OnGround":   int8(1),
+		"Air":        int16(300),
+		"Pos":        mcstatus.NBTList{0.5, 64.0, -12.5},
+		"UUID":       []int32{1, -2, 3},
+		"Empty":      mcstatus.NBTList{},
+		"Flag":       int8(1),
+		"Inventory": mcstatus.NBTList{
+			mcstatus.NBTCompound{"Slot": int8(0), "id": "minecraft:stone", "count": int32(64)},
+		},
+		"Nested": mcstatus.NBTCompound{"a": mcstatus.NBTCompound{"b": []int8{1, 2}}},
+		"Longs":  []int64{1},
+		"Big":    "3000000000",
+	}
+
+	if !reflect.DeepEqual(compound, expected) {
+		t.Fatalf("unexpected compound: %#v", compound)
+	}
+
+	if health, ok := compound.Float("Health"); !ok || health != 20 {
+		t.Fatalf("unexpected health: %f", health)
+	}
+
+	if air, ok := compound.Int("Air"); !ok || air != 300 {
+		t.Fatalf("unexpected air: %d", air)
+	}
+
+	for _, value := range []string{`{a: 1`, `{a 1}`, `[1, 2`, `'unterminated`, `{} extra`, `[I; 1b, "a"]`, `{id: minecraft:stone}`} {
+		if _, err = mcstatus.ParseSNBT(value); !errors.Is(err, mcstatus.ErrInvalidSNBT) {
+			t.Fatalf("expected an error for %q, got %v", value, err)
+		}
+	}
+}
+
+func TestRCONDataGet(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcstatus.NewRCONServer("password", func(addr net.Addr, command string) string {
+		switch command {
+		case "data get entity Notch":
+			return `Notch has the following entity data: {Pos: [0.5d, 64.0d, 0.5d], Health: 20.0f}`
+		case "data get block 1 2 3":
+			return `1, 2, 3 has the following block data: {id: "minecraft:chest", Items: []}`
+		}
+
+		return "No entity was found"
+	})
+
+	go server.Serve(l)
+
+	defer server.Close()
+
+	client := mcstatus.NewRCON()
+
+	if err = client.Dial("127.0.0.1", uint16(l.Addr().(*net.TCPAddr).Port)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	if err = client.Login("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	entity, err := client.DataGetEntity("Notch")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pos, ok := entity.List("Pos"); !ok || len(pos) != 3 || pos[1] != 64.0 {
+		t.Fatalf("unexpected entity data: %#v", entity)
+	}
+
+	block, err := client.DataGetBlock(1, 2, 3)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, _ := block.String("id"); id != "minecraft:chest" {
+		t.Fatalf("unexpected block data: %#v", block)
+	}
+
+	if _, err = client.DataGetEntity("jeb_"); !errors.Is(err, mcstatus.ErrEntityNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
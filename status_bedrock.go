@@ -1,7 +1,6 @@
 package mcstatus
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"fmt"
@@ -14,27 +13,46 @@ import (
 
 var (
 	defaultBedrockStatusOptions = BedrockStatusOptions{
-		EnableSRV:  true,
-		Timeout:    time.Second * 5,
-		ClientGUID: 0,
+		EnableSRV:      true,
+		Timeout:        time.Second * 5,
+		ClientGUID:     0,
+		Samples:        1,
+		SampleInterval: time.Millisecond * 200,
+		SampleTimeout:  time.Second,
+		MaxAttempts:    3,
+		RetryInterval:  time.Millisecond * 500,
 	}
 	bedrockMagic = []byte{0x00, 0xFF, 0xFF, 0x00, 0xFE, 0xFE, 0xFE, 0xFE, 0xFD, 0xFD, 0xFD, 0xFD, 0x12, 0x34, 0x56, 0x78}
 )
 
 type BedrockStatusResponse struct {
-	ServerGUID      int64      `json:"server_guid"`
-	Edition         *string    `json:"edition"`
-	MOTD            *MOTD      `json:"motd"`
-	ProtocolVersion *int64     `json:"protocol_version"`
-	Version         *string    `json:"version"`
-	OnlinePlayers   *int64     `json:"online_players"`
-	MaxPlayers      *int64     `json:"max_players"`
-	ServerID        *string    `json:"server_id"`
-	Gamemode        *string    `json:"gamemode"`
-	GamemodeID      *int64     `json:"gamemode_id"`
-	PortIPv4        *uint16    `json:"port_ipv4"`
-	PortIPv6        *uint16    `json:"port_ipv6"`
-	SRVResult       *SRVRecord `json:"srv_result"`
+	ServerGUID      int64                `json:"server_guid"`
+	Edition         *string              `json:"edition"`
+	MOTD            *MOTD                `json:"motd"`
+	ProtocolVersion *int64               `json:"protocol_version"`
+	Version         *string              `json:"version"`
+	OnlinePlayers   *int64               `json:"online_players"`
+	MaxPlayers      *int64               `json:"max_players"`
+	ServerID        *string              `json:"server_id"`
+	Gamemode        *string              `json:"gamemode"`
+	GamemodeID      *int64               `json:"gamemode_id"`
+	PortIPv4        *uint16              `json:"port_ipv4"`
+	PortIPv6        *uint16              `json:"port_ipv6"`
+	SRVResult       *SRVRecord           `json:"srv_result"`
+	Latency         time.Duration        `json:"latency"`
+	LatencyStats    *BedrockLatencyStats `json:"latency_stats"`
+	Attempts        int                  `json:"attempts"`
+}
+
+// BedrockLatencyStats contains the round trip statistics of a Bedrock status request that sent multiple pings
+type BedrockLatencyStats struct {
+	Sent       int           `json:"sent"`
+	Received   int           `json:"received"`
+	Min        time.Duration `json:"min"`
+	Avg        time.Duration `json:"avg"`
+	Max        time.Duration `json:"max"`
+	Jitter     time.Duration `json:"jitter"`
+	PacketLoss float64       `json:"packet_loss"`
 }
 
 func (r BedrockStatusResponse) String() string {
@@ -88,12 +106,27 @@ func (r BedrockStatusResponse) String() string {
 }
 
 type BedrockStatusOptions struct {
-	EnableSRV  bool
-	Timeout    time.Duration
-	ClientGUID int64
+	EnableSRV      bool
+	Timeout        time.Duration
+	ClientGUID     int64
+	Samples        int
+	SampleInterval time.Duration
+	SampleTimeout  time.Duration
+	MaxAttempts    int
+	RetryInterval  time.Duration
 }
 
-// StatusBedrock retrieves the status of a Bedrock Minecraft server
+type bedrockPong struct {
+	PingTime   int64
+	ServerGUID int64
+	ServerID   string
+}
+
+// StatusBedrock retrieves the status of a Bedrock Minecraft server. The latency is measured using the time
+// echoed back by the server, and if more than one sample is requested the pings are sent at the sample
+// interval and the latency statistics of all samples are included in the response. A sample that is not
+// answered within the sample timeout is counted as lost. When a single sample is requested, the ping is
+// retransmitted with backoff until a pong is received or the attempts run out.
 func StatusBedrock(host string, port uint16, options ...BedrockStatusOptions) (*BedrockStatusResponse, error) {
 	opts := parseBedrockStatusOptions(options...)
 
@@ -121,104 +154,255 @@ func StatusBedrock(host string, port uint16, options ...BedrockStatusOptions) (*
 
 	defer conn.Close()
 
-	r := bufio.NewReader(conn)
-
 	if err = conn.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
 		return nil, err
 	}
 
-	// Unconnected ping packet
-	// https://wiki.vg/Raknet_Protocol#Unconnected_Ping
-	{
-		buf := &bytes.Buffer{}
+	samples := opts.Samples
 
-		// Packet ID - byte
-		if err := buf.WriteByte(0x01); err != nil {
-			return nil, err
-		}
+	if samples < 1 {
+		samples = 1
+	}
 
-		// Time - int64
-		if err := binary.Write(buf, binary.BigEndian, time.Now().UnixNano()/int64(time.Millisecond)); err != nil {
-			return nil, err
-		}
+	pongs := make(chan *bedrockPong)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
 
-		// Magic - bytes
-		if _, err := buf.Write(bedrockMagic); err != nil {
-			return nil, err
-		}
+	defer close(done)
 
-		// Client GUID - int64
-		if err := binary.Write(buf, binary.BigEndian, opts.ClientGUID); err != nil {
-			return nil, err
-		}
+	go (func() {
+		data := make([]byte, 1<<16)
 
-		if _, err := io.Copy(conn, buf); err != nil {
-			return nil, err
-		}
-	}
+		for {
+			n, err := conn.Read(data)
 
-	var serverGUID int64
-	var serverID string
+			if err != nil {
+				readErr <- err
 
-	// Unconnected pong packet
-	// https://wiki.vg/Raknet_Protocol#Unconnected_Pong
-	{
-		// Type - byte
-		{
-			v, err := r.ReadByte()
+				return
+			}
+
+			pong, err := readBedrockPong(data[:n])
 
 			if err != nil {
-				return nil, err
+				continue
 			}
 
-			if v != 0x1C {
-				return nil, ErrUnexpectedResponse
+			select {
+			case pongs <- pong:
+			case <-done:
+				return
 			}
 		}
+	})()
+
+	sent := make(map[int64]time.Time)
+	order := make([]int64, 0, samples)
+	rtts := make(map[int64]time.Duration)
+
+	var first *bedrockPong
+
+	sampleTimer := time.NewTimer(0)
+	defer sampleTimer.Stop()
+
+	// lost fires once the last sample has been sent and had its sample timeout to be answered, any sample that
+	// has not been answered by then is lost, as it was sent even earlier
+	var lost <-chan time.Time
+
+	for len(rtts) < samples {
+		select {
+		case <-sampleTimer.C:
+			{
+				pingTime := time.Now().UnixNano() / int64(time.Millisecond)
+
+				// Every sample needs a unique time so the pong can be matched to the ping it answers
+				for {
+					if _, ok := sent[pingTime]; !ok {
+						break
+					}
 
-		// Time - int64
-		{
-			data := make([]byte, 8)
+					pingTime++
+				}
 
-			if _, err := r.Read(data); err != nil {
-				return nil, err
+				// Unconnected ping packet
+				// https://wiki.vg/Raknet_Protocol#Unconnected_Ping
+				if err := writeBedrockPing(conn, pingTime, opts.ClientGUID); err != nil {
+					return nil, err
+				}
+
+				sent[pingTime] = time.Now()
+				order = append(order, pingTime)
+
+				if samples > 1 {
+					if len(order) < samples {
+						sampleTimer.Reset(opts.SampleInterval)
+					} else {
+						lostTimer := time.NewTimer(opts.SampleTimeout)
+						defer lostTimer.Stop()
+
+						lost = lostTimer.C
+					}
+				} else if len(order) < opts.MaxAttempts && opts.RetryInterval > 0 {
+					sampleTimer.Reset(retryBackoff(opts.RetryInterval, len(order)))
+				}
 			}
-		}
+		case pong := <-pongs:
+			{
+				sentAt, ok := sent[pong.PingTime]
 
-		// Server GUID - int64
-		{
-			if err := binary.Read(r, binary.BigEndian, &serverGUID); err != nil {
-				return nil, err
+				if !ok {
+					continue
+				}
+
+				if _, ok = rtts[pong.PingTime]; ok {
+					continue
+				}
+
+				rtts[pong.PingTime] = time.Since(sentAt)
+
+				if first == nil {
+					first = pong
+				}
 			}
-		}
+		case <-lost:
+			{
+				if first == nil {
+					return nil, ErrTimeout
+				}
 
-		// Magic - bytes
-		{
-			data := make([]byte, 16)
+				samples = len(rtts)
+			}
+		case err := <-readErr:
+			{
+				if first == nil {
+					return nil, err
+				}
 
-			if _, err := r.Read(data); err != nil {
-				return nil, err
+				// Force the loop to end, any sample that has not been answered yet is considered lost
+				samples = len(rtts)
 			}
 		}
+	}
 
-		// Server ID - string
-		{
-			var length uint16
+	response, err := parseBedrockStatus(first.ServerGUID, first.ServerID)
 
-			if err := binary.Read(r, binary.BigEndian, &length); err != nil {
-				return nil, err
-			}
+	if err != nil {
+		return nil, err
+	}
 
-			data := make([]byte, length)
+	response.SRVResult = srvResult
+	response.Attempts = len(order)
 
-			if _, err = r.Read(data); err != nil {
-				return nil, err
-			}
+	stats := calculateBedrockLatencyStats(order, rtts)
+
+	response.Latency = stats.Avg
+
+	if opts.Samples > 1 {
+		response.LatencyStats = &stats
+	}
+
+	return response, nil
+}
+
+// writeBedrockPing writes an unconnected ping packet
+// https://wiki.vg/Raknet_Protocol#Unconnected_Ping
+func writeBedrockPing(w io.Writer, pingTime, clientGUID int64) error {
+	buf := &bytes.Buffer{}
+
+	// Packet ID - byte
+	if err := buf.WriteByte(0x01); err != nil {
+		return err
+	}
+
+	// Time - int64
+	if err := binary.Write(buf, binary.BigEndian, pingTime); err != nil {
+		return err
+	}
+
+	// Magic - bytes
+	if _, err := buf.Write(bedrockMagic); err != nil {
+		return err
+	}
+
+	// Client GUID - int64
+	if err := binary.Write(buf, binary.BigEndian, clientGUID); err != nil {
+		return err
+	}
 
-			serverID = string(data)
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// readBedrockPong parses an unconnected pong packet from a datagram
+// https://wiki.vg/Raknet_Protocol#Unconnected_Pong
+func readBedrockPong(data []byte) (*bedrockPong, error) {
+	r := bytes.NewReader(data)
+
+	pong := &bedrockPong{}
+
+	// Type - byte
+	{
+		v, err := r.ReadByte()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if v != 0x1C {
+			return nil, ErrUnexpectedResponse
+		}
+	}
+
+	// Time - int64
+	{
+		if err := binary.Read(r, binary.BigEndian, &pong.PingTime); err != nil {
+			return nil, err
+		}
+	}
+
+	// Server GUID - int64
+	{
+		if err := binary.Read(r, binary.BigEndian, &pong.ServerGUID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Magic - bytes
+	{
+		data := make([]byte, 16)
+
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(data, bedrockMagic) {
+			return nil, ErrUnexpectedResponse
 		}
 	}
 
+	// Server ID - string
+	{
+		var length uint16
+
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, length)
+
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		pong.ServerID = string(data)
+	}
+
+	return pong, nil
+}
+
+// parseBedrockStatus parses the semicolon separated server ID string sent in an unconnected pong
+func parseBedrockStatus(serverGUID int64, serverID string) (*BedrockStatusResponse, error) {
 	response := &BedrockStatusResponse{
 		ServerGUID:      serverGUID,
 		Edition:         nil,
@@ -232,7 +416,7 @@ func StatusBedrock(host string, port uint16, options ...BedrockStatusOptions) (*
 		GamemodeID:      nil,
 		PortIPv4:        nil,
 		PortIPv6:        nil,
-		SRVResult:       srvResult,
+		SRVResult:       nil,
 	}
 
 	splitID := strings.Split(serverID, ";")
@@ -373,6 +557,62 @@ func StatusBedrock(host string, port uint16, options ...BedrockStatusOptions) (*
 	return response, nil
 }
 
+// calculateBedrockLatencyStats calculates the statistics of the samples, in the order they were sent
+func calculateBedrockLatencyStats(order []int64, rtts map[int64]time.Duration) BedrockLatencyStats {
+	stats := BedrockLatencyStats{
+		Sent:     len(order),
+		Received: len(rtts),
+	}
+
+	if stats.Sent > 0 {
+		stats.PacketLoss = float64(stats.Sent-stats.Received) / float64(stats.Sent) * 100
+	}
+
+	if stats.Received < 1 {
+		return stats
+	}
+
+	var total, totalVariation, previous time.Duration
+
+	count := 0
+
+	for _, pingTime := range order {
+		rtt, ok := rtts[pingTime]
+
+		if !ok {
+			continue
+		}
+
+		if count == 0 || rtt < stats.Min {
+			stats.Min = rtt
+		}
+
+		if rtt > stats.Max {
+			stats.Max = rtt
+		}
+
+		if count > 0 {
+			if rtt > previous {
+				totalVariation += rtt - previous
+			} else {
+				totalVariation += previous - rtt
+			}
+		}
+
+		total += rtt
+		previous = rtt
+		count++
+	}
+
+	stats.Avg = total / time.Duration(stats.Received)
+
+	if stats.Received > 1 {
+		stats.Jitter = totalVariation / time.Duration(stats.Received-1)
+	}
+
+	return stats
+}
+
 func parseBedrockStatusOptions(opts ...BedrockStatusOptions) BedrockStatusOptions {
 	if len(opts) < 1 {
 		options := BedrockStatusOptions(defaultBedrockStatusOptions)
@@ -382,5 +622,11 @@ func parseBedrockStatusOptions(opts ...BedrockStatusOptions) BedrockStatusOption
 		return options
 	}
 
-	return opts[0]
+	options := opts[0]
+
+	if options.SampleTimeout <= 0 {
+		options.SampleTimeout = defaultBedrockStatusOptions.SampleTimeout
+	}
+
+	return options
 }
@@ -1,8 +1,12 @@
 package mcstatus_test
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/PassTheMayo/mcstatus/v3"
 )
@@ -16,3 +20,136 @@ func TestBedrockStatus(t *testing.T) {
 
 	fmt.Println(response)
 }
+
+func serveTestBedrockPong(conn net.PacketConn, drop int) {
+	data := make([]byte, 1500)
+
+	for {
+		n, addr, err := conn.ReadFrom(data)
+
+		if err != nil {
+			return
+		}
+
+		if n < 25 || data[0] != 0x01 {
+			continue
+		}
+
+		if drop > 0 {
+			drop--
+
+			continue
+		}
+
+		serverID := "MCPE;Dedicated Server;589;1.20.0;2;10;13253860892328930865;Bedrock level;Survival;1;19132;19133;"
+
+		buf := &bytes.Buffer{}
+		buf.WriteByte(0x1C)
+		buf.Write(data[1:9])
+		binary.Write(buf, binary.BigEndian, int64(1234))
+		buf.Write(data[9:25])
+		binary.Write(buf, binary.BigEndian, uint16(len(serverID)))
+		buf.WriteString(serverID)
+
+		conn.WriteTo(buf.Bytes(), addr)
+	}
+}
+
+func TestBedrockStatusSamples(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestBedrockPong(conn, 0)
+	response, err := mcstatus.StatusBedrock("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockStatusOptions{
+		EnableSRV:      false,
+		Timeout:        time.Second * 5,
+		ClientGUID:     2,
+		Samples:        5,
+		SampleInterval: time.Millisecond * 10,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.ServerGUID != 1234 || response.OnlinePlayers == nil || *response.OnlinePlayers != 2 {
+		t.Fatalf("unexpected response: %s", response)
+	}
+
+	if response.LatencyStats == nil || response.LatencyStats.Sent != 5 || response.LatencyStats.Received != 5 || response.LatencyStats.PacketLoss != 0 {
+		t.Fatalf("unexpected latency stats: %+v", response.LatencyStats)
+	}
+
+	if response.Latency <= 0 || response.LatencyStats.Min > response.LatencyStats.Avg || response.LatencyStats.Avg > response.LatencyStats.Max {
+		t.Fatalf("inconsistent latency: %s %+v", response.Latency, response.LatencyStats)
+	}
+}
+
+func TestBedrockStatusSampleLoss(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestBedrockPong(conn, 1)
+
+	start := time.Now()
+
+	response, err := mcstatus.StatusBedrock("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockStatusOptions{
+		EnableSRV:      false,
+		Timeout:        time.Second * 5,
+		ClientGUID:     2,
+		Samples:        5,
+		SampleInterval: time.Millisecond * 10,
+		SampleTimeout:  time.Millisecond * 200,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A lost sample only waits for its sample timeout, not for the whole timeout
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Fatalf("waited %s for a lost sample", elapsed)
+	}
+
+	if response.LatencyStats == nil || response.LatencyStats.Sent != 5 || response.LatencyStats.Received != 4 || response.LatencyStats.PacketLoss != 20 {
+		t.Fatalf("unexpected latency stats: %+v", response.LatencyStats)
+	}
+}
+
+func TestBedrockStatusRetransmission(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go serveTestBedrockPong(conn, 2)
+
+	response, err := mcstatus.StatusBedrock("127.0.0.1", uint16(conn.LocalAddr().(*net.UDPAddr).Port), mcstatus.BedrockStatusOptions{
+		EnableSRV:     false,
+		Timeout:       time.Second * 5,
+		ClientGUID:    2,
+		MaxAttempts:   5,
+		RetryInterval: time.Millisecond * 50,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", response.Attempts)
+	}
+}
@@ -0,0 +1,359 @@
+package mcstatus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+var (
+	defaultStatusProxyOptions = StatusProxyOptions{
+		Transforms: nil,
+		StatusOptions: JavaStatusOptions{
+			EnableSRV:       false,
+			Timeout:         time.Second * 5,
+			ProtocolVersion: 47,
+		},
+		Timeout: time.Second * 5,
+	}
+)
+
+// StatusTransform modifies the status of the backend server before it is sent to the client
+type StatusTransform func(status *JavaStatusResponse) error
+
+type StatusProxyOptions struct {
+	Transforms    []StatusTransform
+	StatusOptions JavaStatusOptions
+	Timeout       time.Duration
+}
+
+// StatusProxy is a passthrough proxy that forwards login traffic to the backend server untouched, but
+// answers status requests itself using a transformed copy of the backend server's status
+type StatusProxy struct {
+	host    string
+	port    uint16
+	options StatusProxyOptions
+	server  tcpServer
+}
+
+type javaStatusPayload struct {
+	Version     interface{}               `json:"version"`
+	Players     javaStatusPayloadPlayers  `json:"players"`
+	Description interface{}               `json:"description"`
+	Favicon     string                    `json:"favicon,omitempty"`
+	ModInfo     *javaStatusPayloadModInfo `json:"modinfo,omitempty"`
+}
+
+type javaStatusPayloadPlayers struct {
+	Max    int         `json:"max"`
+	Online int         `json:"online"`
+	Sample interface{} `json:"sample,omitempty"`
+}
+
+type javaStatusPayloadModInfo struct {
+	Type string                 `json:"type"`
+	List []javaStatusPayloadMod `json:"modList"`
+}
+
+type javaStatusPayloadMod struct {
+	ID      string `json:"modid"`
+	Version string `json:"version"`
+}
+
+// NewStatusProxy creates a new status rewriting proxy in front of the backend server
+func NewStatusProxy(host string, port uint16, options ...StatusProxyOptions) *StatusProxy {
+	return &StatusProxy{
+		host:    host,
+		port:    port,
+		options: parseStatusProxyOptions(options...),
+	}
+}
+
+// ListenAndServe listens on the TCP address and proxies every accepted connection
+func (p *StatusProxy) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+
+	if err != nil {
+		return err
+	}
+
+	return p.Serve(l)
+}
+
+// Serve accepts connections from the listener and proxies them until the proxy is closed
+func (p *StatusProxy) Serve(l net.Listener) error {
+	return p.server.serve(l, p.handle)
+}
+
+// Close stops accepting connections and closes all connections that are being proxied
+func (p *StatusProxy) Close() error {
+	return p.server.close()
+}
+
+func (p *StatusProxy) handle(client net.Conn) {
+	if err := client.SetReadDeadline(time.Now().Add(p.options.Timeout)); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(client)
+
+	// Legacy server list pings cannot be rewritten, so they are passed through to the backend
+	if b, err := r.Peek(1); err == nil && b[0] == 0xFE {
+		p.forward(client, r, nil)
+
+		return
+	}
+
+	handshake, raw, err := readHandshakePacket(r)
+
+	if err != nil {
+		return
+	}
+
+	if handshake.NextState != 1 {
+		p.forward(client, r, raw)
+
+		return
+	}
+
+	p.serveStatus(client, r, handshake)
+}
+
+func (p *StatusProxy) forward(client net.Conn, r *bufio.Reader, handshake []byte) {
+	backend, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", p.host, p.port), p.options.Timeout)
+
+	if err != nil {
+		return
+	}
+
+	if !p.server.track(backend) {
+		backend.Close()
+
+		return
+	}
+
+	defer p.server.untrack(backend)
+	defer backend.Close()
+
+	if err = client.SetReadDeadline(time.Time{}); err != nil {
+		return
+	}
+
+	if _, err = backend.Write(handshake); err != nil {
+		return
+	}
+
+	pipeConns(client, r, backend)
+}
+
+func (p *StatusProxy) serveStatus(client net.Conn, r *bufio.Reader, handshake *Handshake) {
+	// Request packet
+	// https://wiki.vg/Server_List_Ping#Request
+	{
+		// Packet length - varint
+		if _, _, err := readVarInt(r); err != nil {
+			return
+		}
+
+		// Packet ID - varint
+		packetID, _, err := readVarInt(r)
+
+		if err != nil || packetID != 0x00 {
+			return
+		}
+	}
+
+	statusOptions := p.options.StatusOptions
+	statusOptions.ProtocolVersion = int(handshake.ProtocolVersion)
+
+	status, err := Status(p.host, p.port, statusOptions)
+
+	if err != nil {
+		return
+	}
+
+	for _, transform := range p.options.Transforms {
+		if err = transform(status); err != nil {
+			return
+		}
+	}
+
+	data, err := encodeJavaStatus(status)
+
+	if err != nil {
+		return
+	}
+
+	// Response packet
+	// https://wiki.vg/Server_List_Ping#Response
+	{
+		buf := &bytes.Buffer{}
+
+		// Packet ID - varint
+		if _, err := writeVarInt(0x00, buf); err != nil {
+			return
+		}
+
+		// JSON response - string
+		if err := writeString(string(data), buf); err != nil {
+			return
+		}
+
+		if err := writePacket(buf, client); err != nil {
+			return
+		}
+	}
+
+	var payload int64
+
+	// Ping packet
+	// https://wiki.vg/Server_List_Ping#Ping
+	{
+		// Packet length - varint
+		if _, _, err := readVarInt(r); err != nil {
+			return
+		}
+
+		// Packet ID - varint
+		packetID, _, err := readVarInt(r)
+
+		if err != nil || packetID != 0x01 {
+			return
+		}
+
+		// Payload - int64
+		if err := binary.Read(r, binary.BigEndian, &payload); err != nil {
+			return
+		}
+	}
+
+	// Pong packet
+	// https://wiki.vg/Server_List_Ping#Pong
+	{
+		buf := &bytes.Buffer{}
+
+		// Packet ID - varint
+		if _, err := writeVarInt(0x01, buf); err != nil {
+			return
+		}
+
+		// Payload - int64
+		if err := binary.Write(buf, binary.BigEndian, payload); err != nil {
+			return
+		}
+
+		writePacket(buf, client)
+	}
+}
+
+// encodeJavaStatus serialises the status into the JSON format sent by servers in the response packet
+func encodeJavaStatus(status *JavaStatusResponse) ([]byte, error) {
+	payload := javaStatusPayload{
+		Version: status.Version,
+		Players: javaStatusPayloadPlayers{
+			Max:    status.Players.Max,
+			Online: status.Players.Online,
+		},
+		Description: motdChatComponent(status.MOTD),
+	}
+
+	if len(status.Players.Sample) > 0 {
+		payload.Players.Sample = status.Players.Sample
+	}
+
+	if status.Favicon.Exists() {
+		payload.Favicon = status.Favicon.raw
+	}
+
+	if status.ModInfo != nil {
+		mods := make([]javaStatusPayloadMod, 0)
+
+		for _, mod := range status.ModInfo.Mods {
+			mods = append(mods, javaStatusPayloadMod{
+				ID:      mod.ID,
+				Version: mod.Version,
+			})
+		}
+
+		payload.ModInfo = &javaStatusPayloadModInfo{
+			Type: status.ModInfo.Type,
+			List: mods,
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// motdChatComponent converts the MOTD into a chat component with one child component for every formatting item
+func motdChatComponent(m MOTD) map[string]interface{} {
+	extra := make([]interface{}, 0)
+
+	for _, v := range m.Tree {
+		if len(v.Text) < 1 {
+			continue
+		}
+
+		component := map[string]interface{}{
+			"text": v.Text,
+		}
+
+		if len(v.Color) > 0 {
+			component["color"] = v.Color
+		}
+
+		if v.Obfuscated {
+			component["obfuscated"] = true
+		}
+
+		if v.Bold {
+			component["bold"] = true
+		}
+
+		if v.Strikethrough {
+			component["strikethrough"] = true
+		}
+
+		if v.Underline {
+			component["underlined"] = true
+		}
+
+		if v.Italic {
+			component["italic"] = true
+		}
+
+		extra = append(extra, component)
+	}
+
+	result := map[string]interface{}{
+		"text": "",
+	}
+
+	if len(extra) > 0 {
+		result["extra"] = extra
+	}
+
+	return result
+}
+
+func parseStatusProxyOptions(opts ...StatusProxyOptions) StatusProxyOptions {
+	if len(opts) < 1 {
+		return defaultStatusProxyOptions
+	}
+
+	options := opts[0]
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultStatusProxyOptions.Timeout
+	}
+
+	// The protocol version is always replaced with the one the client sent, so only the timeout needs a default
+	if options.StatusOptions.Timeout <= 0 {
+		options.StatusOptions.Timeout = defaultStatusProxyOptions.StatusOptions.Timeout
+	}
+
+	return options
+}
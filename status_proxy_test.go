@@ -0,0 +1,124 @@
+package mcstatus_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PassTheMayo/mcstatus/v3"
+)
+
+func writeTestPacket(w io.Writer, data []byte) error {
+	length := make([]byte, binary.MaxVarintLen32)
+	n := binary.PutUvarint(length, uint64(len(data)))
+
+	_, err := w.Write(append(length[:n], data...))
+
+	return err
+}
+
+func serveTestStatus(conn net.Conn, status string) error {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	if _, err := mcstatus.ReadHandshake(r); err != nil {
+		return err
+	}
+
+	// Request packet
+	if _, err := io.ReadFull(r, make([]byte, 2)); err != nil {
+		return err
+	}
+
+	length := make([]byte, binary.MaxVarintLen32)
+	n := binary.PutUvarint(length, uint64(len(status)))
+
+	if err := writeTestPacket(conn, append(append([]byte{0x00}, length[:n]...), status...)); err != nil {
+		return err
+	}
+
+	// Ping packet
+	ping := make([]byte, 10)
+
+	if _, err := io.ReadFull(r, ping); err != nil {
+		return err
+	}
+
+	return writeTestPacket(conn, ping[1:])
+}
+
+func TestStatusProxy(t *testing.T) {
+	backend, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer backend.Close()
+
+	go (func() {
+		conn, err := backend.Accept()
+
+		if err != nil {
+			return
+		}
+
+		serveTestStatus(conn, `{"version":{"name":"Paper 1.20.4","protocol":765},"players":{"max":20,"online":3},"description":"A Minecraft Server"}`)
+	})()
+
+	backendAddr := backend.Addr().(*net.TCPAddr)
+
+	proxy := mcstatus.NewStatusProxy("127.0.0.1", uint16(backendAddr.Port), mcstatus.StatusProxyOptions{
+		Transforms: []mcstatus.StatusTransform{
+			func(status *mcstatus.JavaStatusResponse) error {
+				motd, err := mcstatus.ParseMOTD("§cDown for maintenance")
+
+				if err != nil {
+					return err
+				}
+
+				status.MOTD = *motd
+				status.Version.Name = "Hidden"
+				status.Players.Max = 100
+
+				return nil
+			},
+		},
+	})
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go proxy.Serve(listener)
+
+	defer proxy.Close()
+
+	response, err := mcstatus.Status("127.0.0.1", uint16(listener.Addr().(*net.TCPAddr).Port), mcstatus.JavaStatusOptions{
+		EnableSRV:       false,
+		Timeout:         time.Second * 5,
+		ProtocolVersion: 765,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.MOTD.Clean() != "Down for maintenance" {
+		t.Fatalf("unexpected MOTD: %q", response.MOTD.Clean())
+	}
+
+	if response.Version.Name != "Hidden" || response.Version.Protocol != 765 {
+		t.Fatalf("unexpected version: %+v", response.Version)
+	}
+
+	if response.Players.Online != 3 || response.Players.Max != 100 {
+		t.Fatalf("unexpected players: %d/%d", response.Players.Online, response.Players.Max)
+	}
+}
@@ -0,0 +1,81 @@
+package mcstatus
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// exchangeUDP writes the request to the connection and waits for a datagram accepted by the match function.
+// If nothing is accepted the request is written again with an exponentially increasing delay until the
+// maximum attempts have been made or the deadline has passed. Datagrams that are not accepted, such as
+// responses to stale requests, are ignored. The received datagram and the number of attempts are returned.
+func exchangeUDP(conn net.Conn, deadline time.Time, maxAttempts int, retryInterval time.Duration, request func(attempt int) []byte, match func(data []byte) bool) ([]byte, int, error) {
+	data := make([]byte, 1<<16)
+	attempts := 0
+
+	for {
+		if _, err := conn.Write(request(attempts)); err != nil {
+			return nil, attempts, err
+		}
+
+		attempts++
+
+		readDeadline := deadline
+
+		if attempts < maxAttempts && retryInterval > 0 {
+			if next := time.Now().Add(retryBackoff(retryInterval, attempts)); next.Before(deadline) {
+				readDeadline = next
+			}
+		}
+
+		if err := conn.SetReadDeadline(readDeadline); err != nil {
+			return nil, attempts, err
+		}
+
+		for {
+			n, err := conn.Read(data)
+
+			if err != nil {
+				var netErr net.Error
+
+				if errors.As(err, &netErr) && netErr.Timeout() && readDeadline.Before(deadline) {
+					break
+				}
+
+				return nil, attempts, err
+			}
+
+			if match(data[:n]) {
+				result := make([]byte, n)
+
+				copy(result, data[:n])
+
+				return result, attempts, nil
+			}
+		}
+	}
+}
+
+// datagramWriter writes every call to Write as a single datagram to the address
+type datagramWriter struct {
+	conn net.PacketConn
+	addr net.Addr
+}
+
+func (w *datagramWriter) Write(data []byte) (int, error) {
+	return w.conn.WriteTo(data, w.addr)
+}
+
+// retryBackoff returns the delay to wait for a response after the attempt before sending the next one
+func retryBackoff(interval time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		return interval
+	}
+
+	if attempt > 16 {
+		attempt = 16
+	}
+
+	return interval << (attempt - 1)
+}